@@ -40,6 +40,7 @@ func (p *Program) TokenLiteral() string {
 }
 
 // for say x = 5, x is identifier and 5 is value(expression)
+// const x = 5 is a let statement with a const token
 type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
@@ -146,7 +147,16 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
-func (pe *PrefixExpression) String() string       { return pe.Token.Literal }
+func (pe *PrefixExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pe.Operator)
+	out.WriteString(pe.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
 
 type InfixExpression struct {
 	Token    token.Token // operator token
@@ -178,6 +188,15 @@ func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+// null literal
+type Null struct {
+	Token token.Token
+}
+
+func (n *Null) expressionNode()      {}
+func (n *Null) TokenLiteral() string { return n.Token.Literal }
+func (n *Null) String() string       { return n.Token.Literal }
+
 // if statements
 type IfExpression struct {
 	Token       token.Token
@@ -204,6 +223,79 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// condition ? consequence : alternative
+type TernaryExpression struct {
+	Token       token.Token // the ? token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (te *TernaryExpression) expressionNode()      {}
+func (te *TernaryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TernaryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(te.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(te.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(te.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// x = v, self.n += 1, ...
+// Operator is the assignment token, the target an identifier or a member expression
+type AssignExpression struct {
+	Token    token.Token // the = or compound assignment token
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " " + ae.Operator + " " + ae.Value.String() + ")"
+}
+
+// match value { pattern => result, ... }
+type MatchExpression struct {
+	Token token.Token // match token
+	Value Expression
+	Arms  []*MatchArm
+}
+
+// pattern => result, a result that isn't a block is wrapped in one
+type MatchArm struct {
+	Pattern Expression
+	Body    *BlockStatement
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.Pattern.String()+" => "+arm.Body.String())
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Value.String())
+	if len(arms) == 0 {
+		out.WriteString(" {}")
+	} else {
+		out.WriteString(" { " + strings.Join(arms, ", ") + " }")
+	}
+
+	return out.String()
+}
+
 // block statements (inside if statement)
 type BlockStatement struct {
 	Token      token.Token // the { token
@@ -227,6 +319,7 @@ type FunctionLiteral struct {
 	Token      token.Token // fn token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string // name of the binding when the literal is the value of a let statement
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -248,6 +341,160 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// struct Point { x, y }
+type StructStatement struct {
+	Token  token.Token // struct token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) statementNode()       {}
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	out.WriteString(ss.TokenLiteral() + " ")
+	out.WriteString(ss.Name.String())
+	if len(fields) == 0 {
+		out.WriteString(" {}")
+	} else {
+		out.WriteString(" { " + strings.Join(fields, ", ") + " }")
+	}
+
+	return out.String()
+}
+
+// fn fact(n) { ... } declares fact in the enclosing block
+type FunctionStatement struct {
+	Token    token.Token // fn token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode()       {}
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FunctionStatement) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range fs.Function.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(fs.TokenLiteral() + " ")
+	out.WriteString(fs.Name.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(fs.Function.Body.String())
+
+	return out.String()
+}
+
+// class Counter extends Base { init(n) { ... } inc() { ... } }
+// methods are function literals named after the method
+type ClassStatement struct {
+	Token   token.Token // class token
+	Name    *Identifier
+	Parent  *Identifier // nil without extends
+	Methods []*FunctionLiteral
+}
+
+func (cs *ClassStatement) statementNode()       {}
+func (cs *ClassStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ClassStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(cs.TokenLiteral() + " ")
+	out.WriteString(cs.Name.String())
+	if cs.Parent != nil {
+		out.WriteString(" extends " + cs.Parent.String())
+	}
+	out.WriteString(" {")
+	for _, m := range cs.Methods {
+		params := []string{}
+		for _, p := range m.Parameters {
+			params = append(params, p.String())
+		}
+		out.WriteString(" " + m.Name + "(" + strings.Join(params, ", ") + ") " + m.Body.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// enum Result { Ok(v), Err(e) }
+type EnumStatement struct {
+	Token    token.Token // enum token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+// variant of an enum statement, Fields is empty for a variant without payload
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (es *EnumStatement) statementNode()       {}
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStatement) String() string {
+	var out bytes.Buffer
+
+	variants := []string{}
+	for _, v := range es.Variants {
+		variant := v.Name.String()
+		if len(v.Fields) > 0 {
+			fields := []string{}
+			for _, f := range v.Fields {
+				fields = append(fields, f.String())
+			}
+			variant += "(" + strings.Join(fields, ", ") + ")"
+		}
+		variants = append(variants, variant)
+	}
+
+	out.WriteString(es.TokenLiteral() + " ")
+	out.WriteString(es.Name.String())
+	if len(variants) == 0 {
+		out.WriteString(" {}")
+	} else {
+		out.WriteString(" { " + strings.Join(variants, ", ") + " }")
+	}
+
+	return out.String()
+}
+
+// import "path/to/mod.pika" as name
+type ImportStatement struct {
+	Token token.Token // the import token
+	Path  *StringLiteral
+	Name  *Identifier
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " \"" + is.Path.Value + "\" as " + is.Name.String() + ";"
+}
+
+// export in front of a let, const or fn declaration at the top level of a module
+type ExportStatement struct {
+	Token       token.Token // the export token
+	Declaration Statement
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Declaration.String()
+}
+
 // type call expressions
 type CallExpression struct {
 	Token     token.Token // the '(' token
@@ -277,6 +524,9 @@ func (ce *CallExpression) String() string {
 type StringLiteral struct {
 	Token token.Token
 	Value string
+	// set by the evaluator to the string object every evaluation of the literal
+	// returns, so hash lookups with it reuse the key the string caches
+	Cache interface{}
 }
 
 func (sl *StringLiteral) expressionNode()      {}
@@ -306,10 +556,34 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// sets, #{1, 2, 3}
+type SetLiteral struct {
+	Token    token.Token // the #{ token
+	Elements []Expression
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type IndexExpression struct {
-	Token token.Token // the [ token
-	Left  Expression
-	Index Expression
+	Token    token.Token // the [ token, or ?[ for optional access
+	Left     Expression
+	Index    Expression
+	Optional bool // yields null instead of indexing when Left is null
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -319,6 +593,9 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -326,10 +603,40 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
-// hashmaps
+// a.name, or a?.name for optional access
+type MemberExpression struct {
+	Token    token.Token // the . or ?. token
+	Left     Expression
+	Member   *Identifier
+	Optional bool // yields null instead of accessing the member when Left is null
+	// set by the evaluator to the member name as a string object, looking the
+	// member up in a hash, module or instance then reuses the key it caches
+	Cache interface{}
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(me.Left.String())
+	out.WriteString(me.Token.Literal)
+	out.WriteString(me.Member.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// hashmaps, the pairs are kept in the order they are written in
 type HashLiteral struct {
 	Token token.Token // the { token
-	Pairs map[Expression]Expression
+	Pairs []HashLiteralPair
+}
+
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -338,8 +645,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
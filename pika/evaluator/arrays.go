@@ -0,0 +1,80 @@
+package evaluator
+
+import "pika/object"
+
+// builtins building arrays in one go, so the standard library doesn't have to
+// grow them with push, which copies the whole array every time
+func init() {
+	builtins["range"] = &object.Builtin{Fn: builtinRange}
+	builtins["slice"] = &object.Builtin{Fn: builtinSlice}
+}
+
+// range(start, end): array of the integers from start up to but not including end
+func builtinRange(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	start, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("first argument to `range` must be INTEGER, got %s", args[0].Type())
+	}
+	end, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to `range` must be INTEGER, got %s", args[1].Type())
+	}
+
+	length := int64(0)
+	if end.Value > start.Value {
+		length = end.Value - start.Value
+	}
+	if length < 0 {
+		// end - start overflowed
+		return newError("range from %d to %d is too large", start.Value, end.Value)
+	}
+
+	if err := rt.Alloc(object.ArraySize(int(length))); err != nil {
+		return err
+	}
+	elements := make([]object.Object, length)
+	for i := range elements {
+		elements[i] = &object.Integer{Value: start.Value + int64(i)}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// slice(arr, from, to?): array of the elements of arr from index from up to but
+// not including to, which defaults to the length of arr
+// both are clamped to the bounds of arr like the positions of substr
+func builtinSlice(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to `slice` must be ARRAY, got %s", args[0].Type())
+	}
+	from, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to `slice` must be INTEGER, got %s", args[1].Type())
+	}
+
+	length := int64(len(arr.Elements))
+	start := clamp(from.Value, 0, length)
+	end := length
+	if len(args) == 3 {
+		to, ok := args[2].(*object.Integer)
+		if !ok {
+			return newError("third argument to `slice` must be INTEGER, got %s", args[2].Type())
+		}
+		end = clamp(to.Value, start, length)
+	}
+
+	if err := rt.Alloc(object.ArraySize(int(end - start))); err != nil {
+		return err
+	}
+	elements := make([]object.Object, end-start)
+	copy(elements, arr.Elements[start:end])
+
+	return &object.Array{Elements: elements}
+}
@@ -0,0 +1,83 @@
+package evaluator
+
+import (
+	"pika/ast"
+	"pika/object"
+	"strings"
+)
+
+// x = v rebinds x in the scope that declared it and a.n = v sets field n of an instance
+// x op= v is x = x op v, the value of an assignment is the value assigned
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		return evalAssignIdentifier(node, target.Value, env)
+	case *ast.MemberExpression:
+		return evalAssignMember(node, target, env)
+	default:
+		return newError("cannot assign to %s", node.Target.String())
+	}
+}
+
+// only bindings made with let, fn, a parameter and so on can be assigned, constants can't
+func evalAssignIdentifier(node *ast.AssignExpression, name string, env *object.Environment) object.Object {
+	scope := env.Resolve(name)
+	if scope == nil {
+		return newError("cannot assign to undeclared variable %s", name)
+	}
+	if scope.IsConst(name) {
+		return newError("cannot assign to constant %s", name)
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+	if node.Operator != "=" {
+		current, _ := scope.Get(name)
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val, env.Runtime())
+		if isError(val) {
+			return val
+		}
+	}
+
+	scope.Set(name, val)
+	return val
+}
+
+// structs, hashes and modules can't be changed, only instances of classes can
+func evalAssignMember(node *ast.AssignExpression, target *ast.MemberExpression, env *object.Environment) object.Object {
+	left := Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+	name := memberName(target)
+	instance, ok := left.(*object.Instance)
+	if !ok {
+		return newError("cannot assign to member %s of %s", name.Value, typeName(left))
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	current, ok := instance.Field(name)
+	if node.Operator != "=" {
+		if !ok {
+			return newError("%s has no field %s", instance.Class.Name, name.Value)
+		}
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val, env.Runtime())
+		if isError(val) {
+			return val
+		}
+	}
+
+	if !ok {
+		if err := env.Runtime().Alloc(object.PairSize); err != nil {
+			return err
+		}
+	}
+	instance.SetField(name, val)
+	return val
+}
@@ -0,0 +1,43 @@
+package evaluator
+
+import (
+	"context"
+	"pika/ast"
+	"pika/object"
+	"time"
+)
+
+// limits on a single evaluation, the zero value means no limits
+type Limits struct {
+	MaxSteps int           // maximum number of nodes evaluated
+	Timeout  time.Duration // maximum wall time
+}
+
+// eval node like Eval but stop as soon as ctx is done or one of the limits is exceeded
+// the result is then an *object.Error whose Kind tells which budget ran out
+// meant for running untrusted programs that may never terminate on their own
+// a panic in the evaluator is reported as an error too instead of crashing the host
+func EvalContext(
+	ctx context.Context,
+	node ast.Node,
+	env *object.Environment,
+	limits Limits,
+) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError("internal error: %v", r)
+		}
+	}()
+
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	rt := env.Runtime()
+	rt.SetBudget(ctx, limits.MaxSteps)
+	defer rt.ClearBudget()
+
+	return Eval(node, env)
+}
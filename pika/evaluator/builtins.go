@@ -3,13 +3,14 @@ package evaluator
 import (
 	"fmt"
 	"pika/object"
+	"unicode/utf8"
 )
 
 // map for string names to actual functions
 var builtins = map[string]*object.Builtin{
 	// map "len" to new builtin function object
 	"len": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt *object.Runtime, args ...object.Object) object.Object {
 			// check number of arguments
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
@@ -19,7 +20,12 @@ var builtins = map[string]*object.Builtin{
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				// characters, not bytes
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(arg.Len())}
+			case *object.Set:
+				return &object.Integer{Value: int64(arg.Len())}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
@@ -27,7 +33,7 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"first": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt *object.Runtime, args ...object.Object) object.Object {
 			// check number of arguments
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
@@ -46,7 +52,7 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"last": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt *object.Runtime, args ...object.Object) object.Object {
 			// check number of arguments
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
@@ -65,7 +71,7 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"rest": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt *object.Runtime, args ...object.Object) object.Object {
 			// check number of arguments
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
@@ -78,6 +84,9 @@ var builtins = map[string]*object.Builtin{
 			arr := args[0].(*object.Array)
 			length := len(arr.Elements)
 			if length > 0 {
+				if err := rt.Alloc(object.ArraySize(length - 1)); err != nil {
+					return err
+				}
 				newElements := make([]object.Object, length-1, length)
 				copy(newElements, arr.Elements[1:length]) // copy all elements except first
 				return &object.Array{Elements: newElements}
@@ -87,7 +96,7 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"push": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt *object.Runtime, args ...object.Object) object.Object {
 			// check number of arguments
 			if len(args) != 2 {
 				return newError("wrong number of arguments. got=%d, want=2", len(args))
@@ -99,6 +108,9 @@ var builtins = map[string]*object.Builtin{
 			arr := args[0].(*object.Array)
 			length := len(arr.Elements)
 
+			if err := rt.Alloc(object.ArraySize(length + 1)); err != nil {
+				return err
+			}
 			newElements := make([]object.Object, length+1)
 			copy(newElements, arr.Elements) // copy all elements of original array
 			newElements[length] = args[1]   // add new element at the end
@@ -108,7 +120,7 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"print": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt *object.Runtime, args ...object.Object) object.Object {
 			for _, arg := range args {
 				// print the string representation of each argument
 				fmt.Println(arg.Inspect())
@@ -0,0 +1,62 @@
+package evaluator
+
+import (
+	"pika/ast"
+	"pika/object"
+)
+
+// members, indexes and calls following each other make a chain like a?.b.c[0]()
+// when the value before a ?. or ?[ is null the rest of the chain is skipped and
+// the whole chain is null, so n?.a.b is null for a null n instead of failing on .b
+// only the optional link checks for null, a null a.b still fails in a?.b.c
+
+// value of a member, index or call expression and whether an optional link
+// found null, which skips the links after it
+func evalLink(node ast.Expression, env *object.Environment) (object.Object, bool) {
+	switch node := node.(type) {
+	case *ast.MemberExpression:
+		left, skipped := evalChainLeft(node.Left, env)
+		if skipped || node.Optional && left == NULL {
+			return NULL, true
+		}
+		if isError(left) {
+			return left, false
+		}
+		return evalMemberExpression(left, memberName(node)), false
+
+	case *ast.IndexExpression:
+		left, skipped := evalChainLeft(node.Left, env)
+		if skipped || node.Optional && left == NULL {
+			return NULL, true
+		}
+		if isError(left) {
+			return left, false
+		}
+
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index, false
+		}
+		return evalIndexExpression(left, index, env.Runtime()), false
+
+	case *ast.CallExpression:
+		return evalCall(node, env, false)
+
+	default:
+		return Eval(node, env), false
+	}
+}
+
+// left side of a link, the links before it are evaluated with evalLink
+// and counted as a step each like Eval does
+func evalChainLeft(left ast.Expression, env *object.Environment) (object.Object, bool) {
+	switch left.(type) {
+	case *ast.MemberExpression, *ast.IndexExpression, *ast.CallExpression:
+		if err := env.Runtime().Step(); err != nil {
+			return err, false
+		}
+		return evalLink(left, env)
+	default:
+		return Eval(left, env), false
+	}
+}
@@ -0,0 +1,97 @@
+package evaluator
+
+import (
+	"pika/ast"
+	"pika/object"
+)
+
+// class Counter extends Base { ... } binds Counter to a class that makes instances when called
+// methods close over the scope of the class statement like functions declared there would
+func evalClassStatement(node *ast.ClassStatement, env *object.Environment) object.Object {
+	class := &object.Class{Name: node.Name.Value, Methods: map[string]*object.Function{}}
+
+	if node.Parent != nil {
+		parent := evalIdentifier(node.Parent, env)
+		if isError(parent) {
+			return parent
+		}
+		parentClass, ok := parent.(*object.Class)
+		if !ok {
+			return newError("%s can only extend a class, got %s", class.Name, parent.Type())
+		}
+		class.Parent = parentClass
+	}
+
+	for _, method := range node.Methods {
+		class.Methods[method.Name] = &object.Function{
+			Parameters: method.Parameters,
+			Body:       method.Body,
+			Env:        env,
+			Name:       class.Name + "." + method.Name,
+		}
+	}
+
+	if err := declare(class.Name, class, false, env); err != nil {
+		return err
+	}
+	return nil
+}
+
+// new instance of a class, initialized by the init method of the class or its ancestors
+// a class without init takes no arguments
+func newInstance(class *object.Class, args []object.Object, rt *object.Runtime) object.Object {
+	if err := rt.Alloc(object.HashSize(0)); err != nil {
+		return err
+	}
+	instance := object.NewInstance(class)
+
+	init, defining, ok := class.Method("init")
+	if !ok {
+		if len(args) > 0 {
+			return newError("wrong number of arguments to %s. got=%d, want=0", class.Name, len(args))
+		}
+		return instance
+	}
+
+	result := applyFunction(&object.BoundMethod{Self: instance, Method: init, Class: defining}, args, rt)
+	if isError(result) {
+		return result
+	}
+	return instance
+}
+
+// the method as a function running in an environment enclosing its own,
+// with self bound to the instance and super to the parent of the defining class
+func bindSelf(method *object.BoundMethod) *object.Function {
+	env := object.NewEnclosedEnvironment(method.Method.Env)
+	env.SetConst("self", method.Self)
+	if method.Class.Parent != nil {
+		env.SetConst("super", &object.Super{Self: method.Self, Class: method.Class.Parent})
+	}
+
+	return &object.Function{
+		Parameters: method.Method.Parameters,
+		Body:       method.Method.Body,
+		Env:        env,
+		Name:       method.Method.Name,
+	}
+}
+
+// a.name of an instance is its field name, or else its method name bound to it
+func evalInstanceMember(instance *object.Instance, name *object.String) object.Object {
+	if value, ok := instance.Field(name); ok {
+		return value
+	}
+	if method, defining, ok := instance.Class.Method(name.Value); ok {
+		return &object.BoundMethod{Self: instance, Method: method, Class: defining}
+	}
+	return newError("%s has no field or method %s", instance.Class.Name, name.Value)
+}
+
+// super.name is the method name of the parent class, bound to the same instance
+func evalSuperMember(super *object.Super, name string) object.Object {
+	if method, defining, ok := super.Class.Method(name); ok {
+		return &object.BoundMethod{Self: super.Self, Method: method, Class: defining}
+	}
+	return newError("%s has no method %s", super.Class.Name, name)
+}
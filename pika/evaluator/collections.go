@@ -0,0 +1,348 @@
+package evaluator
+
+import (
+	"pika/object"
+	"sort"
+)
+
+// builtins working on whole arrays, calling back into user functions
+// registered in init since they reach the builtins map again through applyFunction
+func init() {
+	builtins["map"] = &object.Builtin{Fn: builtinMap}
+	builtins["filter"] = &object.Builtin{Fn: builtinFilter}
+	builtins["reduce"] = &object.Builtin{Fn: builtinReduce}
+	builtins["each"] = &object.Builtin{Fn: builtinEach}
+	builtins["find"] = &object.Builtin{Fn: builtinFind}
+	builtins["any"] = &object.Builtin{Fn: builtinAny}
+	builtins["all"] = &object.Builtin{Fn: builtinAll}
+	builtins["zip"] = &object.Builtin{Fn: builtinZip}
+	builtins["enumerate"] = &object.Builtin{Fn: builtinEnumerate}
+	builtins["flatten"] = &object.Builtin{Fn: builtinFlatten}
+	builtins["sort"] = &object.Builtin{Fn: builtinSort}
+}
+
+// map(arr, f): array of f applied to every element
+func builtinMap(rt *object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("map", args)
+	if err != nil {
+		return err
+	}
+
+	if err := rt.Alloc(object.ArraySize(len(arr.Elements))); err != nil {
+		return err
+	}
+	elements := make([]object.Object, len(arr.Elements))
+	for i, el := range arr.Elements {
+		result := applyFunction(fn, []object.Object{el}, rt)
+		if isError(result) {
+			return result
+		}
+		elements[i] = result
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// filter(arr, pred): array of the elements pred holds for
+// pred is called on every element first so the result is only allocated once its size is known
+func builtinFilter(rt *object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("filter", args)
+	if err != nil {
+		return err
+	}
+
+	keep := make([]bool, len(arr.Elements))
+	length := 0
+	for i, el := range arr.Elements {
+		result := applyFunction(fn, []object.Object{el}, rt)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			keep[i] = true
+			length++
+		}
+	}
+
+	if err := rt.Alloc(object.ArraySize(length)); err != nil {
+		return err
+	}
+	elements := make([]object.Object, 0, length)
+	for i, el := range arr.Elements {
+		if keep[i] {
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// reduce(arr, f, initial?): combine the elements from the left with f(acc, el)
+// without an initial value the first element is used, which an empty array doesn't have
+func builtinReduce(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	arr, fn, err := arrayAndFunction("reduce", args[:2])
+	if err != nil {
+		return err
+	}
+
+	elements := arr.Elements
+	var acc object.Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(elements) == 0 {
+			return newError("`reduce` of an empty array needs an initial value")
+		}
+		acc, elements = elements[0], elements[1:]
+	}
+
+	for _, el := range elements {
+		acc = applyFunction(fn, []object.Object{acc, el}, rt)
+		if isError(acc) {
+			return acc
+		}
+	}
+
+	return acc
+}
+
+// each(arr, f): call f with every element for its side effects
+func builtinEach(rt *object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("each", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		if result := applyFunction(fn, []object.Object{el}, rt); isError(result) {
+			return result
+		}
+	}
+
+	return NULL
+}
+
+// find(arr, pred): first element pred holds for, null if there is none
+func builtinFind(rt *object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("find", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		result := applyFunction(fn, []object.Object{el}, rt)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return el
+		}
+	}
+
+	return NULL
+}
+
+// any(arr, pred): whether pred holds for some element, stopping at the first
+func builtinAny(rt *object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("any", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		result := applyFunction(fn, []object.Object{el}, rt)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return TRUE
+		}
+	}
+
+	return FALSE
+}
+
+// all(arr, pred): whether pred holds for every element, stopping at the first it doesn't
+func builtinAll(rt *object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("all", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		result := applyFunction(fn, []object.Object{el}, rt)
+		if isError(result) {
+			return result
+		}
+		if !isTruthy(result) {
+			return FALSE
+		}
+	}
+
+	return TRUE
+}
+
+// zip(a, b): array of [a[i], b[i]] pairs, as long as the shorter array
+func builtinZip(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	a, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to `zip` must be ARRAY, got %s", args[0].Type())
+	}
+	b, ok := args[1].(*object.Array)
+	if !ok {
+		return newError("second argument to `zip` must be ARRAY, got %s", args[1].Type())
+	}
+
+	length := min(len(a.Elements), len(b.Elements))
+	if err := rt.Alloc(object.ArraySize(length) + int64(length)*object.ArraySize(2)); err != nil {
+		return err
+	}
+	pairs := make([]object.Object, length)
+	for i := range pairs {
+		pairs[i] = &object.Array{Elements: []object.Object{a.Elements[i], b.Elements[i]}}
+	}
+
+	return &object.Array{Elements: pairs}
+}
+
+// enumerate(arr): array of [index, element] pairs
+func builtinEnumerate(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `enumerate` must be ARRAY, got %s", args[0].Type())
+	}
+
+	length := len(arr.Elements)
+	if err := rt.Alloc(object.ArraySize(length) + int64(length)*object.ArraySize(2)); err != nil {
+		return err
+	}
+	pairs := make([]object.Object, length)
+	for i, el := range arr.Elements {
+		pairs[i] = &object.Array{Elements: []object.Object{&object.Integer{Value: int64(i)}, el}}
+	}
+
+	return &object.Array{Elements: pairs}
+}
+
+// flatten(arr): array with the elements of nested arrays spliced in, one level deep
+func builtinFlatten(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `flatten` must be ARRAY, got %s", args[0].Type())
+	}
+
+	length := 0
+	for _, el := range arr.Elements {
+		if nested, ok := el.(*object.Array); ok {
+			length += len(nested.Elements)
+		} else {
+			length++
+		}
+	}
+
+	if err := rt.Alloc(object.ArraySize(length)); err != nil {
+		return err
+	}
+	elements := make([]object.Object, 0, length)
+	for _, el := range arr.Elements {
+		if nested, ok := el.(*object.Array); ok {
+			elements = append(elements, nested.Elements...)
+		} else {
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// sort(arr, less?): sorted copy of arr, the sort is stable
+// without less integers and strings are sorted in ascending order,
+// with it a comes before b whenever less(a, b) is truthy
+func builtinSort(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to `sort` must be ARRAY, got %s", args[0].Type())
+	}
+
+	if err := rt.Alloc(object.ArraySize(len(arr.Elements))); err != nil {
+		return err
+	}
+	elements := make([]object.Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+
+	// the first error stops the comparisons from calling back into the program
+	var sortErr object.Object
+	less := func(a, b object.Object) bool {
+		if sortErr != nil {
+			return false
+		}
+		var result object.Object
+		if len(args) == 2 {
+			result = applyFunction(args[1], []object.Object{a, b}, rt)
+		} else {
+			result = compareValues(a, b, rt)
+		}
+		if isError(result) {
+			sortErr = result
+			return false
+		}
+		return isTruthy(result)
+	}
+	sort.SliceStable(elements, func(i, j int) bool { return less(elements[i], elements[j]) })
+
+	if sortErr != nil {
+		return sortErr
+	}
+	return &object.Array{Elements: elements}
+}
+
+// whether a sorts before b in the natural order of integers and strings
+// or by the __lt method of instances
+func compareValues(a, b object.Object, rt *object.Runtime) object.Object {
+	if instance, ok := a.(*object.Instance); ok {
+		if result, ok := callOperatorMethod(instance, "__lt", []object.Object{b}, rt); ok {
+			return result
+		}
+	}
+
+	switch {
+	case a.Type() == object.INTEGER_OBJ && b.Type() == object.INTEGER_OBJ:
+		return nativeBoolToBooleanObject(a.(*object.Integer).Value < b.(*object.Integer).Value)
+	case a.Type() == object.STRING_OBJ && b.Type() == object.STRING_OBJ:
+		return nativeBoolToBooleanObject(a.(*object.String).Value < b.(*object.String).Value)
+	default:
+		return newError("`sort` can't compare %s and %s without a comparison function", typeName(a), typeName(b))
+	}
+}
+
+// check the arguments of builtins called as name(arr, fn)
+func arrayAndFunction(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	switch args[1].(type) {
+	case *object.Function, *object.Builtin, *object.StructType, *object.Class, *object.BoundMethod,
+		*object.Variant:
+	default:
+		return nil, nil, newError("second argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+
+	return arr, args[1], nil
+}
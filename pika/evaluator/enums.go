@@ -0,0 +1,136 @@
+package evaluator
+
+import (
+	"pika/ast"
+	"pika/object"
+	"strings"
+)
+
+// enum Result { Ok(v), Err(e) } binds Result to an enum whose members are its variants
+func evalEnumStatement(node *ast.EnumStatement, env *object.Environment) object.Object {
+	enum := &object.Enum{Name: node.Name.Value}
+	for _, v := range node.Variants {
+		fields := make([]string, len(v.Fields))
+		for i, f := range v.Fields {
+			fields[i] = f.Value
+		}
+		enum.Variants = append(enum.Variants, &object.Variant{Enum: enum, Name: v.Name.Value, Fields: fields})
+	}
+
+	if err := declare(enum.Name, enum, false, env); err != nil {
+		return err
+	}
+	return nil
+}
+
+// E.name is a value of E when the variant has no fields and otherwise the variant
+// itself, which makes values when called with a value for each field
+func evalEnumMember(enum *object.Enum, name string) object.Object {
+	variant, ok := enum.Variant(name)
+	if !ok {
+		return newError("%s has no variant %s", enum.Name, name)
+	}
+	if len(variant.Fields) == 0 {
+		return &object.EnumValue{Variant: variant}
+	}
+	return variant
+}
+
+// value of a variant with fields from a value for each of them, in order
+func newEnumValue(variant *object.Variant, args []object.Object, rt *object.Runtime) object.Object {
+	if len(args) != len(variant.Fields) {
+		return newError("wrong number of arguments to %s.%s. got=%d, want=%d (%s)",
+			variant.Enum.Name, variant.Name, len(args), len(variant.Fields), strings.Join(variant.Fields, ", "))
+	}
+
+	if err := rt.Alloc(object.ArraySize(len(args))); err != nil {
+		return err
+	}
+	values := make([]object.Object, len(args))
+	copy(values, args)
+
+	return &object.EnumValue{Variant: variant, Values: values}
+}
+
+// the first arm whose pattern matches the value and the environment with the
+// names the pattern binds, which only the body of the arm sees
+func evalMatchArm(node *ast.MatchExpression, env *object.Environment) (*ast.MatchArm, *object.Environment, object.Object) {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return nil, nil, value
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		matched, err := matchPattern(arm.Pattern, value, env, armEnv)
+		if err != nil {
+			return nil, nil, err
+		}
+		if matched {
+			return arm, armEnv, nil
+		}
+	}
+
+	return nil, nil, newError("no match arm for %s", value.Inspect())
+}
+
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	arm, armEnv, err := evalMatchArm(node, env)
+	if err != nil {
+		return err
+	}
+	return Eval(arm.Body, armEnv)
+}
+
+// whether value matches pattern, binding the names in it to the parts they match
+// _ matches anything, a name matches anything and binds it, E.Variant(p, ...)
+// matches values of that variant whose fields match the patterns in parentheses,
+// and any other expression matches values equal to it
+func matchPattern(pattern ast.Expression, value object.Object, env, bindings *object.Environment) (bool, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			bindings.Set(pattern.Value, value)
+		}
+		return true, nil
+
+	case *ast.CallExpression:
+		callee := Eval(pattern.Function, env)
+		if isError(callee) {
+			return false, callee
+		}
+		if variant, ok := callee.(*object.Variant); ok {
+			return matchVariant(pattern, variant, value, env, bindings)
+		}
+	}
+
+	expected := Eval(pattern, env)
+	if isError(expected) {
+		return false, expected
+	}
+	if variant, ok := expected.(*object.Variant); ok {
+		return false, newError("pattern %s.%s needs a pattern for each of its fields (%s)",
+			variant.Enum.Name, variant.Name, strings.Join(variant.Fields, ", "))
+	}
+	return valuesEqual(expected, value, env.Runtime())
+}
+
+func matchVariant(pattern *ast.CallExpression, variant *object.Variant, value object.Object, env, bindings *object.Environment) (bool, object.Object) {
+	if len(pattern.Arguments) != len(variant.Fields) {
+		return false, newError("wrong number of fields in pattern for %s.%s. got=%d, want=%d (%s)",
+			variant.Enum.Name, variant.Name, len(pattern.Arguments), len(variant.Fields), strings.Join(variant.Fields, ", "))
+	}
+
+	enumValue, ok := value.(*object.EnumValue)
+	if !ok || enumValue.Variant != variant {
+		return false, nil
+	}
+
+	for i, field := range pattern.Arguments {
+		matched, err := matchPattern(field, enumValue.Values[i], env, bindings)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -4,10 +4,16 @@ import (
 	"fmt"
 	"pika/ast"
 	"pika/object"
+	"pika/token"
+	"strings"
 )
 
 // eval takes in an ast node and returns appropriate object
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if err := env.Runtime().Step(); err != nil {
+		return err
+	}
+
 	switch node := node.(type) {
 
 	// eval statements
@@ -24,22 +30,32 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
+	case *ast.Null:
+		return NULL
+
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return evalPrefixExpression(node.Operator, right, env.Runtime())
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		// the right side of ?? is only evaluated when it is needed
+		if node.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return evalInfixExpression(node.Operator, left, right, env.Runtime())
 
 	// conditionals
 	case *ast.BlockStatement:
@@ -48,6 +64,16 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.TernaryExpression:
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return Eval(node.Consequence, env)
+		}
+		return Eval(node.Alternative, env)
+
 	// return statement
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
@@ -61,29 +87,42 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if err := declare(node.Name.Value, val, node.Token.Type == token.CONST, env); err != nil {
+			return err
+		}
+
+	// declared functions are bound by hoistFunctions when their block starts
+	case *ast.FunctionStatement:
+		return nil
+
+	case *ast.StructStatement:
+		return evalStructStatement(node, env)
+
+	case *ast.ClassStatement:
+		return evalClassStatement(node, env)
+
+	case *ast.EnumStatement:
+		return evalEnumStatement(node, env)
+
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+
+	case *ast.ExportStatement:
+		return Eval(node.Declaration, env)
 
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return cachedString(&node.Cache, node.Value)
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Env: env, Body: body, Name: node.Name}
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args)
+		return evalCallExpression(node, env, false)
 
 	case *ast.ArrayLiteral:
 		// loop over each element and evaluate it in the current env
@@ -91,23 +130,32 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
+		if err := env.Runtime().Alloc(object.ArraySize(len(elements))); err != nil {
+			return err
+		}
 		return &object.Array{Elements: elements}
 
+	// indexes and members are links of chains, see evalLink
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
+		value, _ := evalLink(node, env)
+		return value
 
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		value, _ := evalLink(node, env)
+		return value
 
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 
+	case *ast.SetLiteral:
+		return evalSetLiteral(node, env)
+
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
 	}
 
 	return nil
@@ -116,6 +164,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	if err := hoistFunctions(program.Statements, env); err != nil {
+		return err
+	}
+
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 
@@ -145,11 +197,14 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalPrefixExpression(operator string, right object.Object, rt *object.Runtime) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
+		if instance, ok := right.(*object.Instance); ok {
+			return evalInstanceMinusPrefix(instance, rt)
+		}
 		return evalMinusPrefixOperatorExpression(right)
 
 	default:
@@ -182,22 +237,35 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 func evalInfixExpression(
 	operator string,
 	left, right object.Object,
+	rt *object.Runtime,
 ) object.Object {
+	// classes can overload the operators for their instances, see evalOperatorMethod
+	if result, ok := evalOperatorMethod(operator, left, right, rt); ok {
+		return result
+	}
+
 	switch {
+	case operator == "in":
+		return evalInExpression(left, right, rt)
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
-	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
-	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right, rt)
+	case left.Type() == object.SET_OBJ && right.Type() == object.SET_OBJ:
+		return evalSetInfixExpression(operator, left, right, rt)
+	// arrays are compared element by element, see valuesEqual
+	case operator == "==" || operator == "!=":
+		equal, err := valuesEqual(left, right, rt)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(equal == (operator == "=="))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s",
-			left.Type(), operator, right.Type())
-	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, left, right)
+			typeName(left), operator, typeName(right))
 	default:
 		return newError("unknown operator: %s %s %s",
-			left.Type(), operator, right.Type())
+			typeName(left), operator, typeName(right))
 	}
 }
 
@@ -216,6 +284,9 @@ func evalIntegerInfixExpression(
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -240,14 +311,23 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return Eval(ie.Consequence, newBlockEnvironment(env))
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return Eval(ie.Alternative, newBlockEnvironment(env))
 	} else {
 		return NULL
 	}
 }
 
+// every block gets its own scope so bindings made inside it don't leak out
+// function bodies are the exception since they already run in a fresh environment
+func newBlockEnvironment(env *object.Environment) *object.Environment {
+	if env.Runtime().LegacyBlockScope {
+		return env
+	}
+	return object.NewEnclosedEnvironment(env)
+}
+
 // only FALSE and NULL are falsy
 // everything else is truthy
 func isTruthy(obj object.Object) bool {
@@ -266,6 +346,10 @@ func isTruthy(obj object.Object) bool {
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
+	if err := hoistFunctions(block.Statements, env); err != nil {
+		return err
+	}
+
 	// loop through each statement in block and eval
 	// return if object has return value
 	for _, statement := range block.Statements {
@@ -281,6 +365,61 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// bind every function declared among statements before any of them runs
+// so declarations in the same block can call each other whatever their order
+// a constant of the same block clashes with a declaration even when it comes later
+func hoistFunctions(statements []ast.Statement, env *object.Environment) *object.Error {
+	for _, statement := range statements {
+		if fs, ok := declarationOf(statement).(*ast.FunctionStatement); ok {
+			if declaresConstant(statements, fs.Name.Value) {
+				return newError("cannot redeclare constant %s", fs.Name.Value)
+			}
+			if err := declare(fs.Name.Value, Eval(fs.Function, env), false, env); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func declaresConstant(statements []ast.Statement, name string) bool {
+	for _, statement := range statements {
+		ls, ok := declarationOf(statement).(*ast.LetStatement)
+		if ok && ls.Token.Type == token.CONST && ls.Name.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+// the declaration an export statement wraps, any other statement as is
+func declarationOf(statement ast.Statement) ast.Statement {
+	if es, ok := statement.(*ast.ExportStatement); ok {
+		return es.Declaration
+	}
+	return statement
+}
+
+// bind name in the current scope as a let or const statement does
+// constants can't be redeclared in the scope that declared them
+// but any binding can still be shadowed in an inner scope
+func declare(name string, val object.Object, constant bool, env *object.Environment) *object.Error {
+	if env.IsConst(name) {
+		return newError("cannot redeclare constant %s", name)
+	}
+
+	if env.Declared(name) {
+		env.Runtime().Warn("%s is redeclared in the same scope", name)
+	}
+
+	if constant {
+		env.SetConst(name, val)
+	} else {
+		env.Set(name, val)
+	}
+	return nil
+}
+
 func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
@@ -297,16 +436,32 @@ func evalIdentifier(
 	node *ast.Identifier,
 	env *object.Environment,
 ) object.Object {
-	if val, ok := env.Get(node.Value); ok {
+	if val, ok := lookup(node.Value, env); ok {
 		return val
 	}
 
+	return newError("identifier not found: %s", node.Value)
+}
+
+// value bound to name in scope, the prelude or the builtins
+func lookup(name string, env *object.Environment) (object.Object, bool) {
+	if val, ok := env.Get(name); ok {
+		return val, true
+	}
+
+	// then in the prelude shared by every module, such as the standard library
+	if prelude := env.Runtime().Prelude; prelude != nil {
+		if val, ok := prelude.Get(name); ok {
+			return val, true
+		}
+	}
+
 	// lookup for builtin funcitons as a fallback when identifier not found
-	if builtin, ok := builtins[node.Value]; ok {
-		return builtin
+	if builtin, ok := builtins[name]; ok {
+		return builtin, true
 	}
 
-	return newError("identifier not found: %s", node.Value)
+	return nil, false
 }
 
 func evalExpressions(
@@ -325,22 +480,120 @@ func evalExpressions(
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// evaluate the function and arguments of a call
+// in tail position the call itself is left to the trampoline in applyFunction
+func evalCallExpression(
+	node *ast.CallExpression,
+	env *object.Environment,
+	tail bool,
+) object.Object {
+	result, _ := evalCall(node, env, tail)
+	return result
+}
+
+// evalCallExpression, also telling whether the call was skipped as part of a
+// chain an optional link found null in, see evalLink
+func evalCall(node *ast.CallExpression, env *object.Environment, tail bool) (object.Object, bool) {
+	// x.f(a) may be a method call, see evalMethod
+	var function, receiver object.Object
+	var skipped bool
+	if member, ok := node.Function.(*ast.MemberExpression); ok {
+		function, receiver, skipped = evalMethod(member, env)
+	} else {
+		function, skipped = evalChainLeft(node.Function, env)
+	}
+	if skipped {
+		return NULL, true
+	}
+	if isError(function) {
+		return function, false
+	}
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0], false
+	}
+	if receiver != nil {
+		args = append([]object.Object{receiver}, args...)
+	}
+
+	if tail {
+		return &tailCall{fn: function, args: args}, false
+	}
+	return applyFunction(function, args, env.Runtime()), false
+}
+
+func applyFunction(fn object.Object, args []object.Object, rt *object.Runtime) object.Object {
 
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrappedReturnValue(evaluated)
+		// refuse to go deeper than the runtime allows instead of letting
+		// the go stack overflow and take the whole process down
+		if !rt.EnterCall() {
+			err := newError("stack overflow: maximum call depth of %d exceeded calling %s",
+				rt.MaxDepth, functionName(fn))
+			err.Kind = object.STACK_OVERFLOW
+			return err
+		}
+		defer rt.ExitCall()
+
+		// trampoline: calls in tail position come back as a tailCall and are
+		// run in this same frame instead of growing the go stack
+		for {
+			if len(args) < len(fn.Parameters) {
+				return newError("not enough arguments calling %s. got=%d, want=%d",
+					functionName(fn), len(args), len(fn.Parameters))
+			}
+			extendedEnv := extendFunctionEnv(fn, args)
+			evaluated := evalTailBlock(fn.Body, extendedEnv, true)
+
+			tc, ok := evaluated.(*tailCall)
+			if !ok {
+				return unwrappedReturnValue(evaluated)
+			}
+
+			switch next := tc.fn.(type) {
+			case *object.Function:
+				fn, args = next, tc.args
+			case *object.BoundMethod:
+				fn, args = bindSelf(next), tc.args
+			default:
+				return applyFunction(tc.fn, tc.args, rt)
+			}
+		}
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return fn.Fn(rt, args...)
+
+	case *object.StructType:
+		return newStruct(fn, args, rt)
+
+	case *object.BoundMethod:
+		return applyFunction(bindSelf(fn), args, rt)
+
+	case *object.Class:
+		return newInstance(fn, args, rt)
+
+	case *object.Variant:
+		return newEnumValue(fn, args, rt)
 
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
+// name used to refer to a function in error messages
+func functionName(fn *object.Function) string {
+	if fn.Name != "" {
+		return fn.Name
+	}
+
+	params := []string{}
+	for _, p := range fn.Parameters {
+		params = append(params, p.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
+
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
@@ -360,25 +613,47 @@ func unwrappedReturnValue(obj object.Object) object.Object {
 }
 
 // eval string concatenation
-func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
+func evalStringInfixExpression(
+	operator string,
+	left, right object.Object,
+	rt *object.Runtime,
+) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	// strings are compared by their contents, not by identity
+	switch operator {
+	case "+":
+		if err := rt.Alloc(object.StringSize(len(leftVal) + len(rightVal))); err != nil {
+			return err
+		}
+		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
-
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
 }
 
-func evalIndexExpression(left, index object.Object) object.Object {
+func evalIndexExpression(left, index object.Object, rt *object.Runtime) object.Object {
 	switch {
+	case left.Type() == object.INSTANCE_OBJ:
+		return evalInstanceIndexExpression(left.(*object.Instance), index, rt)
 	// check if left is an array and index is an integer else return error
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
 		// check if left is a hash and index is hashable else return error
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.MODULE_OBJ:
+		return evalModuleIndexExpression(left, index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -399,50 +674,67 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	// map the hash key to the hash pair(key, value)
-	pairs := make(map[object.HashKey]object.HashPair)
-
-	// loop through each key value pair in the hash literal
-	// evaluate the key and value
-	// check if key is hashable
-	// if not return error
-	// else add to pairs map
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	if err := env.Runtime().Alloc(object.HashSize(len(node.Pairs))); err != nil {
+		return err
+	}
+
+	hash := object.NewHash(len(node.Pairs))
+
+	// evaluate each key value pair in the order they are written
+	// the key has to be hashable, a key written twice keeps its
+	// first position and gets the last value
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.ToHashable(key)
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 // eval hash index expression
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.ToHashable(index)
 	if !ok {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	value, ok := hashObject.Get(key)
 	if !ok {
 		return NULL
 	}
 
-	return pair.Value
+	return value
+}
+
+// string object for value kept in cache, made the first time it is needed
+// strings are never changed in place, so every evaluation of a node can share
+// one and its hash key is computed once instead of on every lookup
+func cachedString(cache *interface{}, value string) *object.String {
+	if str, ok := (*cache).(*object.String); ok {
+		return str
+	}
+	str := &object.String{Value: value}
+	*cache = str
+	return str
+}
+
+// name of the member of a.name, see cachedString
+func memberName(node *ast.MemberExpression) *object.String {
+	return cachedString(&node.Cache, node.Member.Value)
 }
@@ -1,10 +1,16 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
 	"pika/lexer"
 	"pika/object"
 	"pika/parser"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -171,6 +177,22 @@ func TestErrorHandling(t *testing.T) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"10 / 0",
+			"division by zero",
+		},
+		{
+			`let h = {"a": null}; h?.a.b`,
+			"cannot access member b of NULL",
+		},
+		{
+			`let h = {"a": null}; h?["a"]["b"]`,
+			"index operator not supported: NULL",
+		},
+		{
+			"let x = 1; x /= 0",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -204,6 +226,121 @@ func TestLetStatement(t *testing.T) {
 	}
 }
 
+func TestBlockScope(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; if (true) { let x = 2; }; x", 1},
+		{"let x = 1; if (false) { 0 } else { let x = 2; }; x", 1},
+		{"let x = 1; if (true) { let x = x + 1; x }", 2},
+		{"if (true) { let y = 2; }; y", "identifier not found: y"},
+		{"if (true) { fn g() { 1 } }; g()", "identifier not found: g"},
+		{"let f = if (true) { let v = 3; fn() { v } }; f()", 3},
+		{"let f = fn(x) { if (x > 0) { let x = 0; }; x }; f(5)", 5},
+		{"let f = fn(n, acc) { if (n == 0) { acc } else { let m = n - 1; f(m, acc + n) } }; f(10, 0)", 55},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got %T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected %q, got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestLegacyBlockScope(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; if (true) { let x = 2; }; x", 2},
+		{"if (true) { let y = 2; }; y", 2},
+		{"let f = fn(x) { if (x > 0) { let x = 0; }; x }; f(5)", 0},
+		{"let n = 0; if (true) { let n = 1; n = 5 }; n", 5},
+		{"if (true) { let m = 1 }; m = 2; m", 2},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		env := object.NewEnvironment()
+		env.Runtime().LegacyBlockScope = true
+
+		testIntegerObject(t, Eval(p.ParseProgram(), env), tt.expected)
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const x = 5; x", 5},
+		{"const x = 5; let x = 6; x", "cannot redeclare constant x"},
+		{"const x = 5; const x = 6; x", "cannot redeclare constant x"},
+		{"const x = 5; fn x() { 6 } x", "cannot redeclare constant x"},
+		{"fn x() { 6 } const x = 5; x", "cannot redeclare constant x"},
+		{"const x = 5; if (true) { let x = 6; x }", 6},
+		{"const x = 5; if (true) { let x = 6; }; x", 5},
+		{"const x = 5; let f = fn(x) { x }; f(6)", 6},
+		{"const x = 5; let f = fn() { const x = 6; x }; f() + x", 11},
+		{"let x = 5; const x = 6; x", 6},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got %T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected %q, got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRedeclarationWarnings(t *testing.T) {
+	input := `
+	let x = 1;
+	let x = 2;
+	let y = 1;
+	if (true) { let y = 2; };
+	let f = fn(y) { let y = 3; y };
+	f(1);
+	`
+
+	var warnings bytes.Buffer
+	env := object.NewEnvironment()
+	env.Runtime().Warnings = &warnings
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	testIntegerObject(t, Eval(p.ParseProgram(), env), 3)
+
+	expected := "warning: x is redeclared in the same scope\n" +
+		"warning: y is redeclared in the same scope\n"
+	if warnings.String() != expected {
+		t.Errorf("wrong warnings. expected %q, got %q", expected, warnings.String())
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -245,6 +382,82 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestArrowFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let add = (x, y) => x + y; add(2, 3)", 5},
+		{"let answer = () => 42; answer()", 42},
+		{"let adder = (x) => (y) => x + y; adder(2)(3)", 5},
+		{"let apply = fn(f, x) { f(x) }; apply((x) => x * x, 7)", 49},
+		{"let abs = (x) => { if (x < 0) { return -x; } x }; abs(-4)", 4},
+		{"let loop = (n) => n == 0 ? 0 : loop(n - 1); loop(100000)", 0},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	fn, ok := testEval("(x) => x * 2").(*object.Function)
+	if !ok {
+		t.Fatalf("arrow function did not evaluate to a Function")
+	}
+	expected := "fn(x) {\n(x * 2)\n}"
+	if fn.Inspect() != expected {
+		t.Errorf("wrong Inspect output. expected %q, got %q", expected, fn.Inspect())
+	}
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn double(x) { x * 2 } double(4)", 8},
+		{"fn fact(n) { if (n == 0) { 1 } else { n * fact(n - 1) } } fact(5)", 120},
+		{`
+		let toInt = fn(b) { if (b) { 1 } else { 0 } };
+		toInt(isEven(10));
+		fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+		fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+		toInt(isOdd(7))
+		`, 1},
+		{"fn outer() { return inner(); fn inner() { 5 } } outer()", 5},
+		{"fn outer() { fn inner() { 5 } } inner()", "identifier not found: inner"},
+		{"fn runaway(n) { 1 + runaway(n) } runaway(1)", "stack overflow: maximum call depth of 10000 exceeded calling runaway"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got %T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected %q, got %q", expected, errObj.Message)
+			}
+		}
+	}
+
+	fn, ok := testEval("fn fact(n) { n } fact").(*object.Function)
+	if !ok {
+		t.Fatalf("declaration did not bind a Function")
+	}
+	if fn.Name != "fact" {
+		t.Errorf("function name wrong. expected %q, got %q", "fact", fn.Name)
+	}
+	expected := "fn fact(n) {\nn\n}"
+	if fn.Inspect() != expected {
+		t.Errorf("wrong Inspect output. expected %q, got %q", expected, fn.Inspect())
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 	let newAdder = fn(x) {
@@ -257,6 +470,1186 @@ func TestClosures(t *testing.T) {
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestRecursionDepthLimit(t *testing.T) {
+	input := `
+	let runaway = fn(n) { 1 + runaway(n + 1) };
+	runaway(0);
+	`
+	evaluated := testEval(input)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got %T(%+v)", evaluated, evaluated)
+	}
+
+	expected := "stack overflow: maximum call depth of 10000 exceeded calling runaway"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected %q, got %q", expected, errObj.Message)
+	}
+}
+
+func TestConfiguredMaxDepth(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Runtime().MaxDepth = 50
+
+	l := lexer.New("let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } };")
+	p := parser.New(l)
+	Eval(p.ParseProgram(), env)
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"count(49)", 49},
+		{"count(50)", "stack overflow: maximum call depth of 50 exceeded calling count"},
+		{"count(10)", 10}, // depth is released after an overflow
+		{"fn(x) { count(x) }(50)", "stack overflow: maximum call depth of 50 exceeded calling count"},
+		{"fn(x) { 1 + fn(y) { count(y) }(x) }(49)", "stack overflow: maximum call depth of 50 exceeded calling count"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		evaluated := Eval(p.ParseProgram(), env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got %T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected %q, got %q", expected, errObj.Message)
+			}
+		}
+
+		if env.Runtime().Depth() != 0 {
+			t.Errorf("call depth not released. got %d", env.Runtime().Depth())
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(1000000)", 0},
+		{"let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); }; sum(100000, 0)", 5000050000},
+		{"let count = fn(n) { if (n > 0) { return count(n - 1); } 42 }; count(100000)", 42},
+		{`
+		let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+		let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+		let toInt = fn(b) { if (b) { 1 } else { 0 } };
+		toInt(even(100001))
+		`, 0},
+		{"let size = fn(arr) { len(arr) }; size([1, 2, 3])", 3},
+		{"let pick = fn(n) { if (n == 0) { first } else { pick(n - 1) } }; pick(20000)([7, 8])", 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestTailCallsDoNotGrowCallDepth(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Runtime().MaxDepth = 10
+
+	input := `
+	let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } };
+	let notTail = fn(n) { if (n == 0) { 0 } else { 1 + notTail(n - 1) } };
+	[loop(1000), notTail(100)]
+	`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	evaluated := Eval(p.ParseProgram(), env)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got %T(%+v)", evaluated, evaluated)
+	}
+
+	expected := "stack overflow: maximum call depth of 10 exceeded calling notTail"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected %q, got %q", expected, errObj.Message)
+	}
+}
+
+func TestEvalContextLimits(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		input        string
+		ctx          context.Context
+		limits       Limits
+		expectedKind object.ErrorKind
+	}{
+		{"let f = fn() { f() }; f()", context.Background(), Limits{MaxSteps: 10000}, object.STEP_LIMIT},
+		{"let f = fn() { f() }; f()", context.Background(), Limits{Timeout: 20 * time.Millisecond}, object.TIMEOUT},
+		{"let f = fn(n) { 1 + f(n) }; f(0)", context.Background(), Limits{Timeout: time.Second}, object.STACK_OVERFLOW},
+		{"1 + 2", canceled, Limits{}, object.CANCELED},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		env := object.NewEnvironment()
+
+		evaluated := EvalContext(tt.ctx, p.ParseProgram(), env, tt.limits)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got %T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind {
+			t.Errorf("wrong error kind for %q. expected %s, got %s (%s)",
+				tt.input, tt.expectedKind, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestEvalContextRecoversFromPanics(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("boom", &object.Builtin{Fn: func(rt *object.Runtime, args ...object.Object) object.Object {
+		panic("boom")
+	}})
+
+	program := parser.New(lexer.New("1 + boom()")).ParseProgram()
+	errObj, ok := EvalContext(context.Background(), program, env, Limits{}).(*object.Error)
+	if !ok || errObj.Message != "internal error: boom" {
+		t.Errorf("panic not turned into an error. got %v", errObj)
+	}
+}
+
+func TestEvalContextWithinLimits(t *testing.T) {
+	input := "let double = fn(x) { x * 2 }; double(21)"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	env := object.NewEnvironment()
+	limits := Limits{MaxSteps: 100, Timeout: time.Second}
+
+	testIntegerObject(t, EvalContext(context.Background(), p.ParseProgram(), env, limits), 42)
+
+	// the budget only applies to the evaluation it was given for
+	l = lexer.New("let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(1000)")
+	p = parser.New(l)
+	testIntegerObject(t, Eval(p.ParseProgram(), env), 0)
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []string{
+		`let grow = fn(s) { grow(s + s) }; grow("pika")`,
+		`let fill = fn(arr) { fill(push(arr, arr)) }; fill([])`,
+		`let nest = fn(h) { nest({"a": h, "b": [h, h, h, h]}) }; nest({})`,
+		`let twice = fn(arr) { twice(flatten([arr, arr])) }; twice([1])`,
+		`let keep = fn(arr) { keep(filter(push(arr, 1), fn(x) { true })) }; keep([])`,
+		`let add = fn(h, i) { add(merge(h, {i: i}), i + 1) }; add({}, 0)`,
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := parser.New(l)
+		env := object.NewEnvironment()
+		env.Runtime().MaxBytes = 1 << 20
+
+		evaluated := Eval(p.ParseProgram(), env)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got %T(%+v)", input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != object.MEMORY_LIMIT {
+			t.Errorf("wrong error kind for %q. expected %s, got %s (%s)",
+				input, object.MEMORY_LIMIT, errObj.Kind, errObj.Message)
+		}
+		if env.Runtime().Allocated() > env.Runtime().MaxBytes {
+			t.Errorf("allocated more than the limit for %q. got %d", input, env.Runtime().Allocated())
+		}
+	}
+}
+
+// hitting the memory limit fails the evaluation that hit it, not later ones
+// that don't allocate, whether or not a budget was set
+func TestMemoryLimitIsNotSticky(t *testing.T) {
+	for _, budget := range []bool{false, true} {
+		env := object.NewEnvironment()
+		env.Runtime().MaxBytes = 1 << 10
+
+		run := func(input string) object.Object {
+			if budget {
+				return EvalContext(context.Background(), parser.New(lexer.New(input)).ParseProgram(), env, Limits{})
+			}
+			return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+		}
+
+		errObj, ok := run(`let grow = fn(s) { grow(s + s) }; grow("pika")`).(*object.Error)
+		if !ok || errObj.Kind != object.MEMORY_LIMIT {
+			t.Fatalf("expected a memory limit error (budget=%t), got %v", budget, errObj)
+		}
+
+		testIntegerObject(t, run("1 + 1"), 2)
+
+		errObj, ok = run(`repeat("pika", 1000)`).(*object.Error)
+		if !ok || errObj.Kind != object.MEMORY_LIMIT {
+			t.Errorf("allocation after the limit should still fail (budget=%t), got %v", budget, errObj)
+		}
+	}
+}
+
+func TestAllocationAccounting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`"pi" + "ka"`, object.StringSize(4)},
+		{`[1, 2, 3]`, object.ArraySize(3)},
+		{`push([1], 2)`, object.ArraySize(1) + object.ArraySize(2)},
+		{`rest([1, 2])`, object.ArraySize(2) + object.ArraySize(1)},
+		{`{"a": 1}`, object.HashSize(1)},
+		{`flatten([[1, 2], 3])`, object.ArraySize(2) + object.ArraySize(2) + object.ArraySize(3)},
+		{`filter([1, 2, 3], fn(x) { x > 1 })`, object.ArraySize(3) + object.ArraySize(2)},
+		{`merge({"a": 1}, {"a": 2, "b": 3})`, object.HashSize(1) + object.HashSize(2) + object.HashSize(2)},
+		{`range(0, 3)`, object.ArraySize(3)},
+		{`slice([1, 2, 3], 1)`, object.ArraySize(3) + object.ArraySize(2)},
+		{`#{1, 2} | #{2, 3}`, object.HashSize(2) + object.HashSize(2) + object.HashSize(3)},
+		{`#{1, 2} & #{2, 3}`, object.HashSize(2) + object.HashSize(2) + object.HashSize(1)},
+		{`#{1, 2} - #{2, 3}`, object.HashSize(2) + object.HashSize(2) + object.HashSize(1)},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		env := object.NewEnvironment()
+
+		Eval(p.ParseProgram(), env)
+
+		if env.Runtime().Allocated() != tt.expected {
+			t.Errorf("wrong allocation for %q. expected %d, got %d",
+				tt.input, tt.expected, env.Runtime().Allocated())
+		}
+	}
+}
+
+func TestNullSafeOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null", nil},
+		{"null == null", true},
+		{"1 == null", false},
+		{"null ?? 5", 5},
+		{"3 ?? 5", 3},
+		{"false ?? 5", false},
+		{`{"a": 1}["b"] ?? 2`, 2},
+		{`{"a": false}["a"] ?? true`, false},
+		{"1 ?? undefinedVar", 1},
+		{`let h = null; h?["a"]`, nil},
+		{`null?[undefinedVar]`, nil},
+		{`let h = {"a": {"b": 2}}; h?.a?.b`, 2},
+		{`let h = {"a": {"b": 2}}; h?.missing?.b`, nil},
+		{`let h = {"a": {"b": 2}}; h?.missing?.b ?? 0`, 0},
+		{`let arr = [[1, 2]]; arr?[0]?[1]`, 2},
+		{`let arr = [[1, 2]]; arr?[5]?[1] ?? -1`, -1},
+		{`let n = null; n?.a.b`, nil},
+		{`let n = null; n?["a"]["b"]`, nil},
+		{`let n = null; n?.a[undefinedVar].c`, nil},
+		{`let n = null; n?.a.b()`, nil},
+		{`let n = null; n?.f(undefinedVar).g`, nil},
+		{`let n = null; n?.a.b ?? 3`, 3},
+		{`let n = null; (n?.a).b`, nil},
+		{`let h = {"a": {"b": 2}}; h?.a.b`, 2},
+		{`let h = {"a": {"b": [1, 2]}}; h?.a.b[1]`, 2},
+		{`let h = {"a": {"b": [1, 2]}}; h?.a.b.len()`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestTernaryExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true ? 10 : 20", 10},
+		{"false ? 10 : 20", 20},
+		{"null ? 10 : 20", 20},
+		{"1 < 2 ? 10 : 20", 10},
+		{"1 > 2 ? 10 : 2 > 1 ? 30 : 40", 30},
+		{"true ? 1 : undefinedVar", 1},
+		{"let max = fn(a, b) { a > b ? a : b }; max(3, 7)", 7},
+		{"let loop = fn(n) { n == 0 ? 0 : loop(n - 1) }; loop(100000)", 0},
+		{"true ? -true : 1", "unknown operator: -BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got %T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected %q, got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestPipeExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = fn(x) { x * 2 }; 5 |> double", 10},
+		{"let double = fn(x) { x * 2 }; 5 |> double |> double", 20},
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(3)", 7},
+		{"[1, 2, 3] |> push(4) |> len", 4},
+		{"let add = fn(a) { fn(b) { a + b } }; 1 |> add(2)()", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"lib/math.pika": `
+			import "helpers.pika" as h;
+			export const pi = 3;
+			export fn double(x) { h["twice"](x) }
+			let secret = 42;
+		`,
+		"lib/helpers.pika": `export let twice = fn(x) { x * 2 };`,
+		"cycle/a.pika":     `import "b.pika" as b;`,
+		"cycle/b.pika":     `import "c.pika" as c;`,
+		"cycle/c.pika":     `import "b.pika" as b;`,
+		"broken.pika":      `let = 1;`,
+		"vendor/util.pika": `export fn inc(x) { x + 1 }`,
+		"shapes.pika":      `export struct Point { x, y } export class Tally { init() { self.n = 0 } add(k) { self.n += k } } export enum Shape { Dot, Square(side) }`,
+	})
+
+	tests := []struct {
+		main     string
+		expected interface{}
+	}{
+		{`import "lib/math.pika" as m; m["double"](m["pi"])`, 6},
+		{`import "lib/math" as m; m["pi"]`, 3},
+		{`import "lib/math.pika" as m; m["secret"]`, "module math.pika has no export secret"},
+		{`import "lib/math.pika" as m; import "lib/../lib/math.pika" as n; m == n`, true},
+		{`import "util.pika" as u; u["inc"](1)`, 2},
+		{`import "missing.pika" as m; 1`, "module not found: missing.pika"},
+		{`import "cycle/a.pika" as a; 1`, "import cycle: b.pika -> c.pika -> b.pika"},
+		{`import "main.pika" as me; 1`, "import cycle: main.pika -> main.pika"},
+		{`import "broken.pika" as b; 1`, "could not parse broken.pika: expected next token to be be IDENT, got = instead; no prefix parse function for = found"},
+		{`import "lib/math.pika" as m; let m = 1; m`, 1},
+		{`import "lib/math.pika" as m; m.double(m.pi)`, 6},
+		{`import "lib/math.pika" as m; m.secret`, "module math.pika has no export secret"},
+		{`import "shapes.pika" as s; s.Point(1, 2).y`, 2},
+		{`import "shapes.pika" as s; let t = s.Tally(); t.add(2); t.add(3)`, 5},
+		{`import "shapes.pika" as s; match s.Shape.Square(4) { s.Shape.Dot => 0, s.Shape.Square(n) => n * n }`, 16},
+		{`import "lib/math.pika" as m; m.secret()`, "module math.pika has no export secret"},
+	}
+
+	for _, tt := range tests {
+		writeFiles(t, dir, map[string]string{"main.pika": tt.main})
+
+		env := object.NewEnvironment()
+		env.Runtime().ModulePath = []string{filepath.Join(dir, "vendor")}
+		evaluated := EvalFile(filepath.Join(dir, "main.pika"), env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got %T(%+v)", tt.main, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected %q, got %q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestModulesAreEvaluatedOnce(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"counter.pika": `export let values = [];`,
+	})
+
+	env := object.NewEnvironment()
+	first := testEvalIn(t, `import "`+filepath.Join(dir, "counter.pika")+`" as c; c`, env)
+	second := testEvalIn(t, `import "`+filepath.Join(dir, "counter.pika")+`" as d; d`, env)
+
+	if first != second {
+		t.Errorf("module was evaluated twice. got %v and %v", first, second)
+	}
+	if len(env.Runtime().Files()) != 0 {
+		t.Errorf("files still being evaluated after import. got %v", env.Runtime().Files())
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len("héllo")`, "5"},
+		{`len("日本語")`, "3"},
+		{`split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`split("añb", "")`, "[a, ñ, b]"},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([], "-")`, ""},
+		{`join(["a", 1], "-")`, "Error: `join` can only join strings, got INTEGER"},
+		{"trim(\"  hi \n\")", "hi"},
+		{`upper("élan")`, "ÉLAN"},
+		{`lower("ÀB")`, "àb"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`contains("pikachu", "ka")`, "true"},
+		{`contains("pikachu", "ze")`, "false"},
+		{`starts_with("pikachu", "pi")`, "true"},
+		{`ends_with("pikachu", "pi")`, "false"},
+		{`index_of("héllo", "l")`, "2"},
+		{`index_of("hello", "z")`, "-1"},
+		{`substr("héllo", 1, 3)`, "éll"},
+		{`substr("héllo", 3)`, "lo"},
+		{`substr("héllo", -2, 2)`, "hé"},
+		{`substr("héllo", 10)`, ""},
+		{`substr("héllo", 2, -1)`, ""},
+		{`substr("abc", 1, 9223372036854775807)`, "bc"},
+		{`substr("abc", 9223372036854775807, 9223372036854775807)`, ""},
+		{`chars("añ")`, "[a, ñ]"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", -1)`, "Error: `repeat` count must not be negative, got -1"},
+		{`pad_left("7", 3, "0")`, "007"},
+		{`pad_left("ñ", 3)`, "  ñ"},
+		{`pad_right("ab", 4, "é")`, "abéé"},
+		{`pad_right("abc", 2)`, "abc"},
+		{`pad_left("a", 3, "xy")`, "Error: third argument to `pad_left` must be a single character, got xy"},
+		{`upper(1)`, "Error: argument to `upper` must be STRING, got INTEGER"},
+		{`replace("a", "b", 1)`, "Error: third argument to `replace` must be STRING, got INTEGER"},
+		{`"abc" == "abc"`, "true"},
+		{`"abc" != "abd"`, "true"},
+		{`"abc" < "abd"`, "true"},
+		{`"b" > "abc"`, "true"},
+		{`"a" == 1`, "false"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`keys({"b": 1, "a": 2, 3: 3, 1: 4, true: 5, false: 6})`, "[b, a, 3, 1, true, false]"},
+		{`values({"b": 1, "a": 2, "c": 3})`, "[1, 2, 3]"},
+		{`entries({"b": 1, "a": 2})`, "[[b, 1], [a, 2]]"},
+		{`keys({})`, "[]"},
+		{`{"b": 1, "a": 2, 1: 3}`, "{b: 1, a: 2, 1: 3}"},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a: 3, b: 2}"},
+		{`has({"a": 1}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`has({"a": null}, "a")`, "true"},
+		{`get({"a": 1}, "a", 0)`, "1"},
+		{`get({"a": 1}, "b", 0)`, "0"},
+		{`get({"a": 1}, "b")`, "null"},
+		{`delete({"a": 1, "b": 2}, "a")`, "{b: 2}"},
+		{`let h = {"a": 1}; delete(h, "a"); h`, "{a: 1}"},
+		{`delete({"a": 1}, "z")`, "{a: 1}"},
+		{`merge({"a": 1, "b": 2}, {"b": 3}, {"c": 4})`, "{a: 1, b: 3, c: 4}"},
+		{`merge({"b": 1}, {"a": 2, "b": 3})`, "{b: 3, a: 2}"},
+		{`delete({"c": 1, "a": 2, "b": 3}, "a")`, "{c: 1, b: 3}"},
+		{`keys([1])`, "Error: argument to `keys` must be HASH, got ARRAY"},
+		{`has([1], 1)`, "Error: first argument to `has` must be HASH, got ARRAY"},
+		{`get({}, [fn(x) { x }])`, "Error: unusable as hash key: ARRAY"},
+		{`merge({}, 1)`, "Error: argument 2 to `merge` must be HASH, got INTEGER"},
+		{`merge({})`, "Error: wrong number of arguments. got=1, want at least 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestArrayKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{[1, 2]: "a"}[[1, 2]]`, "a"},
+		{`{[1, 2]: "a"}[[2, 1]]`, "null"},
+		{`let x = 3; let y = 4; {[x, y]: "cell"}[[3, 4]]`, "cell"},
+		{`{[1, [2, "b"]]: true}[[1, [2, "b"]]]`, "true"},
+		{`{[]: 0}[[]]`, "0"},
+		{`{[1]: "array", 1: "integer"}[[1]]`, "array"},
+		{`{[1, 2]: 1, [1, 2]: 2}`, "{[1, 2]: 2}"},
+		{`has({[true, "x"]: 1}, [true, "x"])`, "true"},
+		{`delete({[0, 0]: 1, [0, 1]: 2}, [0, 0])`, "{[0, 1]: 2}"},
+		{`{[1, fn(x) { x }]: 1}`, "Error: unusable as hash key: ARRAY"},
+		{`{[1, {}]: 1}`, "Error: unusable as hash key: ARRAY"},
+		{`[1, [2, 3]] == [1, [2, 3]]`, "true"},
+		{`[1, 2] == [1, 3]`, "false"},
+		{`[1, 2] != [1, 2, 3]`, "true"},
+		{`["a"] == ["a"]`, "true"},
+		{`[1] == 1`, "false"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#{3, 1, 2, 1}", "#{3, 1, 2}"},
+		{"#{}", "#{}"},
+		{"set()", "#{}"},
+		{`set([1, "a", [1, 2], 1])`, "#{1, a, [1, 2]}"},
+		{"len(#{1, 2, 2})", "2"},
+		{"2 in #{1, 2}", "true"},
+		{"3 in #{1, 2}", "false"},
+		{"[1, 2] in #{[1, 2]}", "true"},
+		{"fn(x) { x } in #{1}", "false"},
+		{`"a" in {"a": 1}`, "true"},
+		{"2 in [1, 2, 3]", "true"},
+		{"[2] in [[1], [2]]", "true"},
+		{"1 in 1", "Error: unknown operator: INTEGER in INTEGER"},
+		{"add(#{1, 2}, 3)", "#{1, 2, 3}"},
+		{"add(#{1, 2}, 1)", "#{1, 2}"},
+		{"let s = #{1}; add(s, 2); s", "#{1}"},
+		{"remove(#{1, 2, 3}, 2)", "#{1, 3}"},
+		{"remove(#{1}, 5)", "#{1}"},
+		{"#{1, 2} | #{2, 3}", "#{1, 2, 3}"},
+		{"#{3, 2, 1} & #{1, 2, 4}", "#{2, 1}"},
+		{"#{1, 2, 3} - #{2}", "#{1, 3}"},
+		{"#{1, 2} == #{2, 1}", "true"},
+		{"#{1, 2} != #{1}", "true"},
+		{"#{1} * #{1}", "Error: unknown operator: SET * SET"},
+		{"#{1} | [1]", "Error: type mismatch: SET | ARRAY"},
+		{"to_array(#{2, 1})", "[2, 1]"},
+		{"#{{}}", "Error: unusable as set element: HASH"},
+		{"set(1)", "Error: argument to `set` must be ARRAY, got INTEGER"},
+		{"add([1], 1)", "Error: first argument to `add` must be SET, got ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y } Point(1, 2)", "Point{x: 1, y: 2}"},
+		{"struct Point { x, y } let p = Point(1, 2); p.x + p.y", "3"},
+		{"struct Point { x, y } Point", "struct Point { x, y }"},
+		{"struct Unit {} Unit()", "Unit{}"},
+		{"struct Line { from, to } struct Point { x, y } Line(Point(0, 0), Point(3, 4)).to.y", "4"},
+		{"struct Point { x, y } Point(1, 2).z", "Error: Point has no field z"},
+		{"struct Point { x, y } Point(1)", "Error: wrong number of arguments to Point. got=1, want=2 (x, y)"},
+		{"struct Point { x, y } Point(1, 2) == Point(1, 2)", "true"},
+		{"struct Point { x, y } Point(1, 2) == Point(2, 1)", "false"},
+		{"struct A { v } struct B { v } A(1) == B(1)", "false"},
+		{"struct Box { v } map([1, 2], Box)", "[Box{v: 1}, Box{v: 2}]"},
+		{"struct Box { v } let b = null; b?.v", "null"},
+		{"struct Box { v } Box(null)?.v", "null"},
+		{`{"name": "pika"}.name`, "pika"},
+		{`{"name": "pika"}.nmae`, "null"},
+		{"1.x", "Error: cannot access member x of INTEGER"},
+		{"const Point = 1; struct Point { x }", "Error: cannot redeclare constant Point"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"pika".len()`, "4"},
+		{`"a,b".split(",")`, "[a, b]"},
+		{"[1, 2, 3].map(fn(x) { x * 2 }).filter(fn(x) { x > 2 })", "[4, 6]"},
+		{"[3, 1, 2].sort().first()", "1"},
+		{`" Pika ".trim().lower().starts_with("pi")`, "true"},
+		{"let double = fn(x) { x * 2 }; 21.double()", "42"},
+		{"let add = fn(a, b) { a + b }; 1.add(2)", "3"},
+		{`{"f": fn(x) { x + 1 }}.f(1)`, "2"},
+		{`{"a": 1}.keys()`, "[a]"},
+		{"struct Counter { step } let next = fn(c, n) { n + c.step }; Counter(5).next(1)", "6"},
+		{"struct Box { get } Box(fn() { 7 }).get()", "7"},
+		{"struct Box { v } Box(1).v()", "Error: not a function: INTEGER"},
+		{"1.nope()", "Error: INTEGER has no member or function nope"},
+		{"struct Box { v } Box(1).nope()", "Error: Box has no member or function nope"},
+		{"let a = null; a?.len()", "null"},
+		{"null.len()", "Error: argument to `len` not supported, got NULL"},
+		{"let count = fn(n, acc) { n == 0 ? acc : (n - 1).count(acc + 1) }; 100000.count(0)", "100000"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; x = 2; x", "2"},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", "6"},
+		{"let x = 1; x = x + 1", "2"},
+		{"let a = 1; let b = 2; a = b = 3; a + b", "6"},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let n = 0; let inc = fn() { n += 1 }; inc(); inc(); n", "2"},
+		{"let n = 0; if (true) { n = 5 }; n", "5"},
+		{"let n = 0; if (true) { let n = 1; n = 5 }; n", "0"},
+		{"let f = fn(n) { if (n > 0) { n = 0 }; n }; f(5)", "0"},
+		{"if (true) { let m = 1 }; m = 2", "Error: cannot assign to undeclared variable m"},
+		{"const x = 1; if (true) { let x = 2; x += 1 }", "3"},
+		{"const x = 1; if (true) { let x = 2; x = 3 }; x", "1"},
+		{"let x = 1; if (true) { const x = 2; x = 3 }", "Error: cannot assign to constant x"},
+		{"let x = 1; if (true) { const x = 2 }; x = 3; x", "3"},
+		{"const x = 1; if (true) { x = 2 }", "Error: cannot assign to constant x"},
+		{"x = 1", "Error: cannot assign to undeclared variable x"},
+		{"const x = 1; x = 2", "Error: cannot assign to constant x"},
+		{"const x = 1; fn() { x += 1 }()", "Error: cannot assign to constant x"},
+		{`let x = 1; x += "a"`, "Error: type mismatch: INTEGER + STRING"},
+		{`let h = {"a": 1}; h.a = 2`, "Error: cannot assign to member a of HASH"},
+		{"struct P { x } let p = P(1); p.x = 2", "Error: cannot assign to member x of P"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestClasses(t *testing.T) {
+	counter := `class Counter {
+  init(start) { self.n = start }
+  inc() { self.n += 1 }
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{counter + "let c = Counter(5); c.inc(); c.inc()", "7"},
+		{counter + "let c = Counter(5); c.inc(); c", "Counter{n: 6}"},
+		{counter + "let c = Counter(1); let d = Counter(10); c.inc(); d.n", "10"},
+		{counter + "Counter", "class Counter"},
+		{counter + "Counter(1).inc", "<method Counter.inc>"},
+		{counter + "let inc = Counter(1).inc; inc(); inc()", "3"},
+		{counter + "[1, 2].map(Counter)", "[Counter{n: 1}, Counter{n: 2}]"},
+		{counter + "let c = Counter(1); c.step = 5; c", "Counter{n: 1, step: 5}"},
+		{counter + "Counter(1) == Counter(1)", "false"},
+		{"class N {} let c = N(); c.me = c; c", "N{me: <cycle>}"},
+		{"class N {} let a = N(); let b = N(); a.next = b; b.prev = a; [a, b]", "[N{next: N{prev: <cycle>}}, N{prev: N{next: <cycle>}}]"},
+		{"struct Box { v } class N {} let c = N(); c.box = Box([c]); c", "N{box: Box{v: [<cycle>]}}"},
+		{"class N {} let c = N(); let d = N(); c.l = d; c.r = d; c", "N{l: N{}, r: N{}}"},
+		{counter + "let c = Counter(1); c == c", "true"},
+		{"class Empty {} Empty()", "Empty{}"},
+		{counter + "class Loud extends Counter { inc() { super.inc() * 10 } } Loud(1).inc()", "20"},
+		{counter + "class Twice extends Counter { inc() { super.inc(); super.inc() } } let t = Twice(0); t.inc(); t", "Twice{n: 2}"},
+		{counter + "class Named extends Counter { init(name) { super.init(0); self.name = name } } Named(\"a\")", "Named{n: 0, name: a}"},
+		{counter + "class Plain extends Counter {} Plain(3).inc()", "4"},
+		{counter + "class Plain extends Counter {} Plain", "class Plain extends Counter"},
+		{"class A { who() { \"A\" } hello() { self.who() } } class B extends A { who() { \"B\" } } B().hello()", "B"},
+		{"class P { get() { self.x } } let p = P(); p.x = 1; p.get()", "1"},
+		{"class Loop { count(n, acc) { n == 0 ? acc : self.count(n - 1, acc + 1) } } Loop().count(100000, 0)", "100000"},
+		{"let step = 3; class S { next(n) { n + step } } S().next(1)", "4"},
+		{counter + "Counter(1).nope", "Error: Counter has no field or method nope"},
+		{counter + "Counter(1).nope()", "Error: Counter has no member or function nope"},
+		{counter + "Counter(1).n += \"a\"", "Error: type mismatch: INTEGER + STRING"},
+		{counter + "Counter(1).m += 1", "Error: Counter has no field m"},
+		{counter + "Counter()", "Error: not enough arguments calling Counter.init. got=0, want=1"},
+		{"class Empty {} Empty(1)", "Error: wrong number of arguments to Empty. got=1, want=0"},
+		{"let A = 1; class B extends A {}", "Error: B can only extend a class, got INTEGER"},
+		{"class B extends A {}", "Error: identifier not found: A"},
+		{"class A { f() { self = 1 } } A().f()", "Error: cannot assign to constant self"},
+		{"class A { f() { super.f() } } A().f()", "Error: identifier not found: super"},
+		{counter + "class B extends Counter { f() { super.nope() } } B(1).f()", "Error: Counter has no method nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	vec := `class Vec {
+  init(x, y) { self.x = x; self.y = y }
+  __add(o) { Vec(self.x + o.x, self.y + o.y) }
+  __sub(o) { Vec(self.x - o.x, self.y - o.y) }
+  __mul(k) { Vec(self.x * k, self.y * k) }
+  __div(k) { Vec(self.x / k, self.y / k) }
+  __neg() { Vec(-self.x, -self.y) }
+  __eq(o) { self.x == o.x ? self.y == o.y : false }
+  __lt(o) { self.x < o.x }
+  __gt(o) { self.x > o.x }
+  __index(i) { i == 0 ? self.x : self.y }
+  __len() { 2 }
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{vec + "Vec(1, 2) + Vec(3, 4)", "Vec{x: 4, y: 6}"},
+		{vec + "Vec(5, 5) - Vec(1, 2)", "Vec{x: 4, y: 3}"},
+		{vec + "Vec(1, 2) * 3", "Vec{x: 3, y: 6}"},
+		{vec + "Vec(4, 6) / 2", "Vec{x: 2, y: 3}"},
+		{vec + "-Vec(1, -2)", "Vec{x: -1, y: 2}"},
+		{vec + "Vec(1, 2) == Vec(1, 2)", "true"},
+		{vec + "Vec(1, 2) != Vec(1, 2)", "false"},
+		{vec + "Vec(1, 2) != Vec(2, 1)", "true"},
+		{vec + "Vec(1, 2) < Vec(2, 0)", "true"},
+		{vec + "Vec(1, 2) > Vec(2, 0)", "false"},
+		{vec + "Vec(7, 8)[1]", "8"},
+		{vec + "len(Vec(7, 8))", "2"},
+		{vec + "Vec(7, 8).len()", "2"},
+		{vec + "let v = Vec(1, 1); v += Vec(1, 2); v *= 2; v", "Vec{x: 4, y: 6}"},
+		{vec + "[Vec(1, 2), Vec(2, 2)].reduce(fn(a, b) { a + b })", "Vec{x: 3, y: 4}"},
+		{vec + "sort([Vec(3, 0), Vec(1, 0), Vec(2, 0)]).map(fn(v) { v.x })", "[1, 2, 3]"},
+		{"class Money { init(c) { self.c = c } __add(o) { Money(self.c + o.c) } } class Euro extends Money {} (Euro(1) + Euro(2)).c", "3"},
+		{vec + "[Vec(1, 2)] == [Vec(1, 2)]", "true"},
+		{vec + "[Vec(1, 2)] != [Vec(2, 1)]", "true"},
+		{vec + "Vec(1, 2) in [Vec(0, 0), Vec(1, 2)]", "true"},
+		{vec + "Vec(1, 2) in [Vec(2, 1)]", "false"},
+		{vec + "struct S { v } S(Vec(1, 2)) == S(Vec(1, 2))", "true"},
+		{vec + "enum Opt { Some(v) } Opt.Some(Vec(1, 2)) == Opt.Some(Vec(1, 2))", "true"},
+		{vec + "match Vec(0, 0) { Vec(1, 1) => 1, Vec(0, 0) => 0, _ => -1 }", "0"},
+		{"class P {} let p = P(); [p] == [p]", "true"},
+		{"class P {} [P()] == [P()]", "false"},
+		{"class P { __eq(o) { o.nope } } [P()] == [P()]", "Error: P has no field or method nope"},
+		{"class P { __eq(o) { o.nope } } P() in [P()]", "Error: P has no field or method nope"},
+		{"class P {} let p = P(); p == p", "true"},
+		{"class P {} P() == P()", "false"},
+		{"class P {} P() + P()", "Error: unknown operator: P + P"},
+		{"class P {} P() + 1", "Error: type mismatch: P + INTEGER"},
+		{"class P {} -P()", "Error: unknown operator: -P"},
+		{"class P {} P()[0]", "Error: index operator not supported: P"},
+		{"class P {} len(P())", "Error: argument to `len` not supported, got P"},
+		{`class P { __len() { "2" } } len(P())`, "Error: __len of P must return INTEGER, got STRING"},
+		{"class P {} sort([P(), P()])", "Error: `sort` can't compare P and P without a comparison function"},
+		{vec + "1 + Vec(1, 2)", "Error: type mismatch: INTEGER + Vec"},
+		{"struct S { a } S(1) + S(2)", "Error: unknown operator: S + S"},
+		{"class P { __add(o) { o.nope } } P() + P()", "Error: P has no field or method nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	enums := "enum Color { Red, Green, Blue } enum Result { Ok(v), Err(e) } "
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{enums + "Color", "enum Color { Red, Green, Blue }"},
+		{enums + "Result", "enum Result { Ok(v), Err(e) }"},
+		{enums + "Color.Red", "Color.Red"},
+		{enums + "Result.Ok", "<variant Result.Ok(v)>"},
+		{enums + "Result.Ok(1)", "Result.Ok(1)"},
+		{enums + "Result.Err([1, 2])", "Result.Err([1, 2])"},
+		{enums + "[1, 2].map(Result.Ok)", "[Result.Ok(1), Result.Ok(2)]"},
+		{enums + "Color.Red == Color.Red", "true"},
+		{enums + "Color.Red != Color.Green", "true"},
+		{enums + "Result.Ok(1) == Result.Ok(1)", "true"},
+		{enums + "Result.Ok(1) == Result.Err(1)", "false"},
+		{enums + "Result.Ok([1]) == Result.Ok([1])", "true"},
+		{enums + "enum Other { Red } Color.Red == Other.Red", "false"},
+		{enums + `{Color.Red: "stop", Color.Green: "go"}[Color.Green]`, "go"},
+		{enums + "{Result.Ok(1): 1, Result.Ok(2): 2}[Result.Ok(2)]", "2"},
+		{enums + "#{Color.Red, Color.Red, Result.Ok(1), Result.Ok(1)}", "#{Color.Red, Result.Ok(1)}"},
+		{enums + "Color.Red in #{Color.Red}", "true"},
+		{enums + "{Result.Ok(fn(x) { x }): 1}", "Error: unusable as hash key: ENUM_VALUE"},
+		{enums + "Color.Purple", "Error: Color has no variant Purple"},
+		{enums + "Result.Ok()", "Error: wrong number of arguments to Result.Ok. got=0, want=1 (v)"},
+		{enums + "Color.Red()", "Error: not a function: ENUM_VALUE"},
+		{enums + "Color.Red + 1", "Error: type mismatch: Color + INTEGER"},
+		{"enum Color { Red } enum Color { Blue } Color", "enum Color { Blue }"},
+		{"const c = 1; enum c { A }", "Error: cannot redeclare constant c"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	enums := "enum Color { Red, Green, Blue } enum Result { Ok(v), Err(e) } enum Shape { Rect(w, h), Circle(r) } "
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{enums + "match Result.Ok(1) { Result.Ok(v) => v + 1, Result.Err(e) => 0 }", "2"},
+		{enums + `match Result.Err("boom") { Result.Ok(v) => v, Result.Err(e) => "failed: " + e }`, "failed: boom"},
+		{enums + "match Shape.Rect(2, 3) { Shape.Circle(r) => 3 * r * r, Shape.Rect(w, h) => w * h }", "6"},
+		{enums + "match Shape.Rect(2, 3) { Shape.Rect(_, h) => h }", "3"},
+		{enums + "match Color.Green { Color.Red => 1, Color.Green => 2, Color.Blue => 3 }", "2"},
+		{enums + "match Color.Blue { Color.Red => 1, _ => 0 }", "0"},
+		{enums + "match Result.Ok(Result.Err(7)) { Result.Ok(Result.Ok(x)) => x, Result.Ok(Result.Err(x)) => -x }", "-7"},
+		{enums + "match Result.Ok(1) { Result.Ok(2) => \"two\", Result.Ok(1) => \"one\" }", "one"},
+		{`match 2 { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match "b" { "a" => 1, other => other + "!" }`, "b!"},
+		{"match [1, 2] { [1, 2] => true, _ => false }", "true"},
+		{"let limit = 3; match 3 { limit => 1 }", "1"},
+		{"match 1 { 1 => { let x = 10; x * 2 } }", "20"},
+		{enums + "let r = Result.Ok(1); match r { Result.Ok(v) => v } ; v", "Error: identifier not found: v"},
+		{enums + "let v = 5; match Result.Ok(1) { Result.Ok(v) => v }; v", "5"},
+		{"let f = fn(x) { match x { 0 => { return 100 } _ => 1 }; 2 }; f(0)", "100"},
+		{"let count = fn(n, acc) { match n { 0 => acc, _ => count(n - 1, acc + 1) } }; count(100000, 0)", "100000"},
+		{enums + "match Color.Red { Color.Blue => 1 }", "Error: no match arm for Color.Red"},
+		{"match 1 {}", "Error: no match arm for 1"},
+		{enums + "match Result.Ok(1) { Result.Ok => 1 }", "Error: pattern Result.Ok needs a pattern for each of its fields (v)"},
+		{enums + "match Result.Ok(1) { Result.Ok(a, b) => 1 }", "Error: wrong number of fields in pattern for Result.Ok. got=2, want=1 (v)"},
+		{"match nope { _ => 1 }", "Error: identifier not found: nope"},
+		{"match 1 { nope.x => 1 }", "Error: identifier not found: nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestHashesWithCollidingKeys(t *testing.T) {
+	defer func(hasher func(string) uint64) { object.StringHasher = hasher }(object.StringHasher)
+	object.StringHasher = func(string) uint64 { return 0 }
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1, "b": 2}["b"]`, "2"},
+		{`{"a": 1, "b": 2}["c"]`, "null"},
+		{`keys({"a": 1, "b": 2, "a": 3})`, "[a, b]"},
+		{`delete({"a": 1, "b": 2}, "a")`, "{b: 2}"},
+		{`has(delete({"a": 1, "b": 2}, "a"), "b")`, "true"},
+		{`merge({"a": 1}, {"b": 2})`, "{a: 1, b: 2}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+// string literals and member names are hashed once, not on every evaluation
+func TestStringKeysAreHashedOnce(t *testing.T) {
+	defer func(hasher func(string) uint64) { object.StringHasher = hasher }(object.StringHasher)
+	hasher := object.StringHasher
+	calls := 0
+	object.StringHasher = func(s string) uint64 {
+		calls++
+		return hasher(s)
+	}
+
+	tests := []struct {
+		input    string
+		maxCalls int
+	}{
+		{`let h = {"key": 1}; map(range(0, 1000), fn(i) { h["key"] })`, 2},
+		{`let h = {"key": 1}; map(range(0, 1000), fn(i) { h.key })`, 2},
+		{`class P { init() { self.n = 0 } get() { self.n } } let p = P(); map(range(0, 1000), fn(i) { p.get() })`, 3},
+		{`class P { inc() { self.n += 1 } } let p = P(); p.n = 0; map(range(0, 1000), fn(i) { p.inc() })`, 3},
+	}
+
+	for _, tt := range tests {
+		calls = 0
+		if evaluated := testEval(tt.input); isError(evaluated) {
+			t.Fatalf("error evaluating %q: %s", tt.input, evaluated.Inspect())
+		}
+		if calls > tt.maxCalls {
+			t.Errorf("strings hashed %d times for %q, want at most %d", calls, tt.input, tt.maxCalls)
+		}
+	}
+}
+
+// h["key"] evaluated again and again, like in a loop of a lookup heavy script
+func BenchmarkHashIndexLiteral(b *testing.B) {
+	// a long key makes the cost of hashing it visible
+	key := strings.Repeat("pika", 64)
+	env := object.NewEnvironment()
+	Eval(parser.New(lexer.New(`let h = {"`+key+`": 1}`)).ParseProgram(), env)
+	program := parser.New(lexer.New(`h["` + key + `"]`)).ParseProgram()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Eval(program, env)
+	}
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], fn(x) { x * 2 })", "[2, 4, 6]"},
+		{"map([], fn(x) { x })", "[]"},
+		{"filter([1, 2, 3, 4], fn(x) { x > 2 })", "[3, 4]"},
+		{"reduce([1, 2, 3, 4], fn(acc, x) { acc + x })", "10"},
+		{`reduce([1, 2], fn(acc, x) { acc + x }, "")`, "Error: type mismatch: STRING + INTEGER"},
+		{"reduce([], fn(acc, x) { acc + x }, 0)", "0"},
+		{"reduce([], fn(acc, x) { acc + x })", "Error: `reduce` of an empty array needs an initial value"},
+		{"let total = 0; each([1, 2], fn(x) { x })", "null"},
+		{"find([1, 2, 3, 4], fn(x) { x > 2 })", "3"},
+		{"find([1, 2], fn(x) { x > 2 })", "null"},
+		{"any([1, 2, 3], fn(x) { x == 2 })", "true"},
+		{"any([], fn(x) { true })", "false"},
+		{"all([1, 2, 3], fn(x) { x > 0 })", "true"},
+		{"all([1, 2, 3], fn(x) { x > 1 })", "false"},
+		{"zip([1, 2, 3], [4, 5])", "[[1, 4], [2, 5]]"},
+		{"enumerate([7, 8])", "[[0, 7], [1, 8]]"},
+		{"flatten([1, [2, [3]], []])", "[1, 2, [3]]"},
+		{"sort([3, 1, 2])", "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{"sort([3, 1, 2], fn(a, b) { a > b })", "[3, 2, 1]"},
+		{"sort([[2, 0], [1, 1], [2, 2], [1, 3]], fn(a, b) { first(a) < first(b) })", "[[1, 1], [1, 3], [2, 0], [2, 2]]"},
+		{`sort([1, "a"])`, "Error: `sort` can't compare STRING and INTEGER without a comparison function"},
+		{"map([1, 2], len)", "Error: argument to `len` not supported, got INTEGER"},
+		{"map([1, 2], fn(x, y) { x })", "Error: not enough arguments calling fn(x, y). got=1, want=2"},
+		{"map(1, fn(x) { x })", "Error: first argument to `map` must be ARRAY, got INTEGER"},
+		{"filter([1], 1)", "Error: second argument to `filter` must be FUNCTION, got INTEGER"},
+		{"let sq = fn(x) { x * x }; [1, 2, 3] |> map(sq) |> reduce(fn(a, b) { a + b })", "14"},
+		{"range(2, 5)", "[2, 3, 4]"},
+		{"range(5, 2)", "[]"},
+		{"range(-9223372036854775807, 9223372036854775807)", "Error: range from -9223372036854775807 to 9223372036854775807 is too large"},
+		{`range(0, "3")`, "Error: second argument to `range` must be INTEGER, got STRING"},
+		{"slice([1, 2, 3, 4], 1, 3)", "[2, 3]"},
+		{"slice([1, 2, 3, 4], 2)", "[3, 4]"},
+		{"slice([1, 2, 3], -5, 9223372036854775807)", "[1, 2, 3]"},
+		{"slice([1, 2, 3], 2, 1)", "[]"},
+		{"slice([1, 2, 3], 5)", "[]"},
+		{"slice(1, 2)", "Error: first argument to `slice` must be ARRAY, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestCollectionBuiltinsPropagateErrors(t *testing.T) {
+	tests := []string{
+		"map([1, 2, 3], fn(x) { if (x == 2) { x + true } else { x } })",
+		"filter([1, 2, 3], fn(x) { x + true })",
+		"each([1, 2, 3], fn(x) { x + true })",
+		"any([1, 2, 3], fn(x) { x + true })",
+		"sort([3, 1, 2], fn(a, b) { a + true })",
+		"map([[1], [2]], fn(x) { map(x, fn(y) { y + true }) })",
+	}
+
+	for _, input := range tests {
+		errObj, ok := testEval(input).(*object.Error)
+		if !ok || errObj.Message != "type mismatch: INTEGER + BOOLEAN" {
+			t.Errorf("error from callback not propagated for %q. got %v", input, errObj)
+		}
+	}
+}
+
+func TestStdlib(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"abs(-3) + sign(-3)", "2"},
+		{"clamp(15, 0, 10)", "10"},
+		{"mod(-7, 3)", "-1"},
+		{"mod(7, 0)", "Error: division by zero"},
+		{"pow(3, 5)", "243"},
+		{"gcd(12, -18)", "6"},
+		{"lcm(4, 6)", "12"},
+		{"map([1, 2, 3], fn(x) { x * 2 })", "[2, 4, 6]"},
+		{"filter(range(0, 10), is_even)", "[0, 2, 4, 6, 8]"},
+		{"reduce([1, 2, 3, 4], fn(acc, x) { acc * 10 + x }, 0)", "1234"},
+		{"sum(range(1, 101))", "5050"},
+		{"reverse([1, 2, 3])", "[3, 2, 1]"},
+		{"take([1, 2, 3], 5)", "[1, 2, 3]"},
+		{"drop([1, 2, 3], 1)", "[2, 3]"},
+		{"includes([1, 2, 3], 2)", "true"},
+		{"compose(fn(x) { x + 1 }, fn(x) { x * 2 })(5)", "11"},
+		{"flip(fn(a, b) { a - b })(1, 10)", "9"},
+		{"curry(fn(a, b) { a * b })(3)(4)", "12"},
+		{"filter([1, 2, 3], negate(is_odd))", "[2]"},
+		{"times(3, identity)", "[0, 1, 2]"},
+		{`join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`capitalize("élan")`, "Élan"},
+		{"lines(\"a\nb\nc\")", "[a, b, c]"},
+		{`words("  to be  or ")`, "[to, be, or]"},
+		{"is_blank(\" \t\")", "true"},
+		{`includes(["a", "b"], "b")`, "true"},
+		{"let sum = fn(arr) { 0 }; sum([1, 2])", "0"},
+		{"sum(range(0, 100001))", "5000050000"},
+		{"let r = reverse(range(0, 100000)); [len(r), first(r), last(r)]", "[100000, 99999, 0]"},
+		{"take(range(0, 100000), 3)", "[0, 1, 2]"},
+		{"take([1, 2], -1)", "[]"},
+		{"len(drop(range(0, 100000), 99997))", "3"},
+		{"drop([1, 2], -1)", "[1, 2]"},
+		{"last(times(100000, fn(i) { i * 2 }))", "199998"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		if err := LoadStdlib(env); err != nil {
+			t.Fatalf("could not load stdlib: %s", err.Message)
+		}
+
+		evaluated := testEvalIn(t, tt.input, env)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+// the helpers building arrays allocate about as much as their result
+func TestStdlibWithinMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"len(range(0, 2000))", "2000"},
+		{"len(reverse(range(0, 2000)))", "2000"},
+		{"len(take(range(0, 2000), 1500))", "1500"},
+		{"len(times(2000, identity))", "2000"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		if err := LoadStdlib(env); err != nil {
+			t.Fatalf("could not load stdlib: %s", err.Message)
+		}
+		env.Runtime().MaxBytes = 1 << 20
+
+		evaluated := testEvalIn(t, tt.input, env)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestStdlibInModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"lib.pika": `export fn total(arr) { sum(arr) }`,
+	})
+
+	env := object.NewEnvironment()
+	if err := LoadStdlib(env); err != nil {
+		t.Fatalf("could not load stdlib: %s", err.Message)
+	}
+	testIntegerObject(t, testEvalIn(t, `import "`+filepath.Join(dir, "lib.pika")+`" as lib; lib.total([1, 2, 3])`, env), 6)
+
+	errObj, ok := testEval("sum([1, 2])").(*object.Error)
+	if !ok || errObj.Message != "identifier not found: sum" {
+		t.Errorf("stdlib visible without being loaded. got %v", errObj)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testEvalIn(t *testing.T, input string, env *object.Environment) object.Object {
+	t.Helper()
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return Eval(program, env)
+}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -0,0 +1,194 @@
+package evaluator
+
+import "pika/object"
+
+// builtins working on hashes
+// hashes are never changed in place, the ones building a hash return a new one
+func init() {
+	builtins["keys"] = &object.Builtin{Fn: builtinKeys}
+	builtins["values"] = &object.Builtin{Fn: builtinValues}
+	builtins["entries"] = &object.Builtin{Fn: builtinEntries}
+	builtins["has"] = &object.Builtin{Fn: builtinHas}
+	builtins["get"] = &object.Builtin{Fn: builtinGet}
+	builtins["delete"] = &object.Builtin{Fn: builtinDelete}
+	builtins["merge"] = &object.Builtin{Fn: builtinMerge}
+}
+
+// keys(h): array of the keys of h, in the order they were added
+func builtinKeys(rt *object.Runtime, args ...object.Object) object.Object {
+	hash, err := hashArgument("keys", args, 1)
+	if err != nil {
+		return err
+	}
+
+	pairs := hash.Pairs()
+	if err := rt.Alloc(object.ArraySize(len(pairs))); err != nil {
+		return err
+	}
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Key
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// values(h): array of the values of h, in the same order as keys(h)
+func builtinValues(rt *object.Runtime, args ...object.Object) object.Object {
+	hash, err := hashArgument("values", args, 1)
+	if err != nil {
+		return err
+	}
+
+	pairs := hash.Pairs()
+	if err := rt.Alloc(object.ArraySize(len(pairs))); err != nil {
+		return err
+	}
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Value
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// entries(h): array of [key, value] pairs, in the same order as keys(h)
+func builtinEntries(rt *object.Runtime, args ...object.Object) object.Object {
+	hash, err := hashArgument("entries", args, 1)
+	if err != nil {
+		return err
+	}
+
+	pairs := hash.Pairs()
+	if err := rt.Alloc(object.ArraySize(len(pairs)) + int64(len(pairs))*object.ArraySize(2)); err != nil {
+		return err
+	}
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// has(h, key): whether h has a value for key
+func builtinHas(rt *object.Runtime, args ...object.Object) object.Object {
+	hash, err := hashArgument("has", args, 2)
+	if err != nil {
+		return err
+	}
+	key, ok := object.ToHashable(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	_, ok = hash.Get(key)
+	return nativeBoolToBooleanObject(ok)
+}
+
+// get(h, key, default?): value of key in h, default or null when there is none
+func builtinGet(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	hash, err := hashArgument("get", args[:2], 2)
+	if err != nil {
+		return err
+	}
+	key, ok := object.ToHashable(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	if value, ok := hash.Get(key); ok {
+		return value
+	}
+	if len(args) == 3 {
+		return args[2]
+	}
+	return NULL
+}
+
+// delete(h, key): copy of h without key
+func builtinDelete(rt *object.Runtime, args ...object.Object) object.Object {
+	hash, err := hashArgument("delete", args, 2)
+	if err != nil {
+		return err
+	}
+	key, ok := object.ToHashable(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	if err := rt.Alloc(object.HashSize(hash.Len())); err != nil {
+		return err
+	}
+	result := object.NewHash(hash.Len())
+	for _, pair := range hash.Pairs() {
+		if !object.KeysEqual(pair.Key.(object.Hashable), key) {
+			result.Set(pair.Key.(object.Hashable), pair.Value)
+		}
+	}
+
+	return result
+}
+
+// merge(a, b, ...): hash with the pairs of every argument
+// when several have the same key it keeps its first position and the last value
+func builtinMerge(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) < 2 {
+		return newError("wrong number of arguments. got=%d, want at least 2", len(args))
+	}
+
+	// count the distinct keys first, a key is new unless an earlier hash has it
+	hashes := make([]*object.Hash, len(args))
+	length := 0
+	for i, arg := range args {
+		hash, ok := arg.(*object.Hash)
+		if !ok {
+			return newError("argument %d to `merge` must be HASH, got %s", i+1, arg.Type())
+		}
+		hashes[i] = hash
+		for _, pair := range hash.Pairs() {
+			if !hasKey(hashes[:i], pair.Key.(object.Hashable)) {
+				length++
+			}
+		}
+	}
+
+	if err := rt.Alloc(object.HashSize(length)); err != nil {
+		return err
+	}
+	result := object.NewHash(length)
+	for _, hash := range hashes {
+		for _, pair := range hash.Pairs() {
+			result.Set(pair.Key.(object.Hashable), pair.Value)
+		}
+	}
+	return result
+}
+
+// whether any of hashes has key
+func hasKey(hashes []*object.Hash, key object.Hashable) bool {
+	for _, hash := range hashes {
+		if _, ok := hash.Get(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// check the arguments of builtins taking n arguments, the first of them a hash
+func hashArgument(name string, args []object.Object, n int) (*object.Hash, *object.Error) {
+	if len(args) != n {
+		return nil, newError("wrong number of arguments. got=%d, want=%d", len(args), n)
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		if n == 1 {
+			return nil, newError("argument to `%s` must be HASH, got %s", name, args[0].Type())
+		}
+		return nil, newError("first argument to `%s` must be HASH, got %s", name, args[0].Type())
+	}
+	return hash, nil
+}
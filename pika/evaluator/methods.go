@@ -0,0 +1,69 @@
+package evaluator
+
+import (
+	"pika/ast"
+	"pika/object"
+)
+
+// function called by x.f(args) and the receiver to pass it as first argument, if any
+// a member f of x is called as is, otherwise x.f(args) is f(x, args) with f looked
+// up like any name, so builtins and library functions chain: arr.map(f).filter(g)
+// skipped is set when an optional link of the chain found null, see evalLink
+func evalMethod(node *ast.MemberExpression, env *object.Environment) (function, receiver object.Object, skipped bool) {
+	left, skipped := evalChainLeft(node.Left, env)
+	if skipped || node.Optional && left == NULL {
+		return NULL, nil, true
+	}
+	if isError(left) {
+		return left, nil, false
+	}
+
+	// modules only have their exports, so a missing one is reported as such
+	name := memberName(node)
+	if _, ok := left.(*object.Module); ok || hasMember(left, name) {
+		return evalMemberExpression(left, name), nil, false
+	}
+
+	if fn, ok := lookup(name.Value, env); ok {
+		return fn, left, false
+	}
+	return newError("%s has no member or function %s", typeName(left), name.Value), nil, false
+}
+
+// whether a.name refers to something of a itself
+func hasMember(obj object.Object, name *object.String) bool {
+	switch obj := obj.(type) {
+	case *object.Struct:
+		_, ok := obj.Of.Field(name.Value)
+		return ok
+	case *object.Hash:
+		_, ok := obj.Get(name)
+		return ok
+	case *object.Instance:
+		_, isField := obj.Field(name)
+		_, _, isMethod := obj.Class.Method(name.Value)
+		return isField || isMethod
+	case *object.Super:
+		// super only has methods, a missing one is reported as such
+		return true
+	case *object.Enum:
+		_, ok := obj.Variant(name.Value)
+		return ok
+	default:
+		return false
+	}
+}
+
+// name of the type of obj for error messages, values of user defined types go by its name
+func typeName(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.Struct:
+		return obj.Of.Name
+	case *object.Instance:
+		return obj.Class.Name
+	case *object.EnumValue:
+		return obj.Variant.Enum.Name
+	default:
+		return string(obj.Type())
+	}
+}
@@ -0,0 +1,212 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"pika/ast"
+	"pika/lexer"
+	"pika/object"
+	"pika/parser"
+	"strings"
+)
+
+// extension tried for imports that don't name one
+const sourceExtension = ".pika"
+
+// read, parse and evaluate the program in the file at path
+// imports in the program are resolved relative to the file
+func EvalFile(path string, env *object.Environment) object.Object {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return newError("could not read %s: %s", path, err)
+	}
+
+	program, errObj := parseFile(abs)
+	if errObj != nil {
+		return errObj
+	}
+
+	rt := env.Runtime()
+	if !rt.EnterFile(abs) {
+		return importCycleError(abs, rt)
+	}
+	defer rt.ExitFile()
+
+	return Eval(program, env)
+}
+
+func parseFile(path string) (*ast.Program, *object.Error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newError("could not read %s: %s", path, err)
+	}
+
+	return parseSource(filepath.Base(path), string(src))
+}
+
+// parse the program in src, name is the file it came from for errors
+func parseSource(name string, src string) (*ast.Program, *object.Error) {
+	l := lexer.New(src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError("could not parse %s: %s", name, strings.Join(p.Errors(), "; "))
+	}
+
+	return program, nil
+}
+
+// evaluate import "path" as name by binding name to the module object
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	module := importModule(node.Path.Value, env.Runtime())
+	if isError(module) {
+		return module
+	}
+
+	if err := declare(node.Name.Value, module, false, env); err != nil {
+		return err
+	}
+	return nil
+}
+
+// a module is evaluated in an environment of its own the first time it is imported
+// every later import gets the same module object back
+func importModule(path string, rt *object.Runtime) object.Object {
+	resolved, ok := resolveModule(path, rt)
+	if !ok {
+		return newError("module not found: %s", path)
+	}
+
+	if module, ok := rt.Module(resolved); ok {
+		return module
+	}
+
+	program, err := parseFile(resolved)
+	if err != nil {
+		return err
+	}
+
+	if !rt.EnterFile(resolved) {
+		return importCycleError(resolved, rt)
+	}
+	defer rt.ExitFile()
+
+	env := object.NewEnvironmentWithRuntime(rt)
+	result := Eval(program, env)
+	if isError(result) {
+		return result
+	}
+
+	module := &object.Module{Path: resolved, Exports: moduleExports(program, env)}
+	rt.AddModule(module)
+
+	return module
+}
+
+// find the file an import refers to
+// relative paths are looked up next to the file doing the import, or the working
+// directory outside of files, and then in each directory of the module path
+func resolveModule(path string, rt *object.Runtime) (string, bool) {
+	names := []string{path}
+	if filepath.Ext(path) == "" {
+		names = append(names, path+sourceExtension)
+	}
+
+	dirs := []string{"."}
+	if files := rt.Files(); len(files) > 0 {
+		dirs[0] = filepath.Dir(files[len(files)-1])
+	}
+	if !filepath.IsAbs(path) {
+		dirs = append(dirs, rt.ModulePath...)
+	}
+
+	for _, dir := range dirs {
+		for _, name := range names {
+			candidate := name
+			if !filepath.IsAbs(name) {
+				candidate = filepath.Join(dir, name)
+			}
+
+			candidate, err := filepath.Abs(candidate)
+			if err != nil {
+				continue
+			}
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// error for importing path while it is still being evaluated
+// listing the chain of imports that lead back to it
+func importCycleError(path string, rt *object.Runtime) *object.Error {
+	files := rt.Files()
+
+	cycle := []string{}
+	for i, f := range files {
+		if f == path {
+			for _, f := range files[i:] {
+				cycle = append(cycle, filepath.Base(f))
+			}
+			break
+		}
+	}
+	cycle = append(cycle, filepath.Base(path))
+
+	return newError("import cycle: %s", strings.Join(cycle, " -> "))
+}
+
+// hash from the names exported at the top level of program to their values in env
+func moduleExports(program *ast.Program, env *object.Environment) *object.Hash {
+	exports := object.NewHash(0)
+
+	for _, statement := range program.Statements {
+		es, ok := statement.(*ast.ExportStatement)
+		if !ok {
+			continue
+		}
+
+		var name string
+		switch declaration := es.Declaration.(type) {
+		case *ast.LetStatement:
+			name = declaration.Name.Value
+		case *ast.FunctionStatement:
+			name = declaration.Name.Value
+		case *ast.StructStatement:
+			name = declaration.Name.Value
+		case *ast.ClassStatement:
+			name = declaration.Name.Value
+		case *ast.EnumStatement:
+			name = declaration.Name.Value
+		}
+
+		value, ok := env.Get(name)
+		if !ok {
+			continue
+		}
+
+		exports.Set(&object.String{Value: name}, value)
+	}
+
+	return exports
+}
+
+// look up an export of a module by name
+func evalModuleIndexExpression(module, index object.Object) object.Object {
+	moduleObject := module.(*object.Module)
+
+	name, ok := index.(*object.String)
+	if !ok {
+		return newError("module exports must be accessed by name, got %s", index.Type())
+	}
+
+	value, ok := moduleObject.Exports.Get(name)
+	if !ok {
+		return newError("module %s has no export %s", filepath.Base(moduleObject.Path), name.Value)
+	}
+
+	return value
+}
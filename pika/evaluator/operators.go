@@ -0,0 +1,132 @@
+package evaluator
+
+import "pika/object"
+
+// len of an instance is overloaded too, wrapped in init since calling
+// the method reaches the builtins map again through applyFunction
+func init() {
+	builtinLen := builtins["len"].Fn
+	builtins["len"] = &object.Builtin{Fn: func(rt *object.Runtime, args ...object.Object) object.Object {
+		if len(args) == 1 {
+			if instance, ok := args[0].(*object.Instance); ok {
+				return evalInstanceLen(instance, rt)
+			}
+		}
+		return builtinLen(rt, args...)
+	}}
+}
+
+// methods a class defines to overload an infix operator for its instances
+var operatorMethods = map[string]string{
+	"+":  "__add",
+	"-":  "__sub",
+	"*":  "__mul",
+	"/":  "__div",
+	"<":  "__lt",
+	">":  "__gt",
+	"==": "__eq",
+	"!=": "__eq",
+}
+
+// left op right when left is an instance whose class overloads op, false otherwise
+// != is the negation of __eq, without __eq instances are only equal to themselves
+func evalOperatorMethod(operator string, left, right object.Object, rt *object.Runtime) (object.Object, bool) {
+	instance, ok := left.(*object.Instance)
+	if !ok {
+		return nil, false
+	}
+	name, ok := operatorMethods[operator]
+	if !ok {
+		return nil, false
+	}
+
+	result, ok := callOperatorMethod(instance, name, []object.Object{right}, rt)
+	if !ok || isError(result) || operator != "!=" {
+		return result, ok
+	}
+	return nativeBoolToBooleanObject(!isTruthy(result)), true
+}
+
+// -x of an instance is its __neg
+func evalInstanceMinusPrefix(instance *object.Instance, rt *object.Runtime) object.Object {
+	if result, ok := callOperatorMethod(instance, "__neg", nil, rt); ok {
+		return result
+	}
+	return newError("unknown operator: -%s", instance.Class.Name)
+}
+
+// x[i] of an instance is its __index(i)
+func evalInstanceIndexExpression(instance *object.Instance, index object.Object, rt *object.Runtime) object.Object {
+	if result, ok := callOperatorMethod(instance, "__index", []object.Object{index}, rt); ok {
+		return result
+	}
+	return newError("index operator not supported: %s", instance.Class.Name)
+}
+
+// len(x) of an instance is its __len, which has to be an integer
+func evalInstanceLen(instance *object.Instance, rt *object.Runtime) object.Object {
+	result, ok := callOperatorMethod(instance, "__len", nil, rt)
+	if !ok {
+		return newError("argument to `len` not supported, got %s", instance.Class.Name)
+	}
+	if !isError(result) && result.Type() != object.INTEGER_OBJ {
+		return newError("__len of %s must return INTEGER, got %s", instance.Class.Name, result.Type())
+	}
+	return result
+}
+
+// call the method name of an instance, false when its class doesn't define it
+func callOperatorMethod(instance *object.Instance, name string, args []object.Object, rt *object.Runtime) (object.Object, bool) {
+	method, defining, ok := instance.Class.Method(name)
+	if !ok {
+		return nil, false
+	}
+	return applyFunction(&object.BoundMethod{Self: instance, Method: method, Class: defining}, args, rt), true
+}
+
+// whether a and b are equal, like object.Equal except that an instance whose
+// class defines __eq is compared with it, also inside arrays, structs and enum values
+// sets and hash keys can't hold instances, so they are left to object.Equal
+func valuesEqual(a, b object.Object, rt *object.Runtime) (bool, object.Object) {
+	switch a := a.(type) {
+	case *object.Instance:
+		result, ok := callOperatorMethod(a, "__eq", []object.Object{b}, rt)
+		if !ok {
+			return object.Equal(a, b), nil
+		}
+		if isError(result) {
+			return false, result
+		}
+		return isTruthy(result), nil
+	case *object.Array:
+		other, ok := b.(*object.Array)
+		if !ok || len(a.Elements) != len(other.Elements) {
+			return false, nil
+		}
+		return allEqual(a.Elements, other.Elements, rt)
+	case *object.Struct:
+		other, ok := b.(*object.Struct)
+		if !ok || a.Of != other.Of {
+			return false, nil
+		}
+		return allEqual(a.Values, other.Values, rt)
+	case *object.EnumValue:
+		other, ok := b.(*object.EnumValue)
+		if !ok || a.Variant != other.Variant {
+			return false, nil
+		}
+		return allEqual(a.Values, other.Values, rt)
+	default:
+		return object.Equal(a, b), nil
+	}
+}
+
+// whether the values of a and b are pairwise equal, a and b have the same length
+func allEqual(a, b []object.Object, rt *object.Runtime) (bool, object.Object) {
+	for i := range a {
+		if equal, err := valuesEqual(a[i], b[i], rt); err != nil || !equal {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,216 @@
+package evaluator
+
+import (
+	"pika/ast"
+	"pika/object"
+)
+
+// builtins working on sets
+// like hashes, sets are never changed in place
+func init() {
+	builtins["set"] = &object.Builtin{Fn: builtinSet}
+	builtins["add"] = &object.Builtin{Fn: builtinAdd}
+	builtins["remove"] = &object.Builtin{Fn: builtinRemove}
+	builtins["to_array"] = &object.Builtin{Fn: builtinToArray}
+}
+
+func evalSetLiteral(node *ast.SetLiteral, env *object.Environment) object.Object {
+	if err := env.Runtime().Alloc(object.HashSize(len(node.Elements))); err != nil {
+		return err
+	}
+
+	set := object.NewSet(len(node.Elements))
+	for _, elementNode := range node.Elements {
+		el := Eval(elementNode, env)
+		if isError(el) {
+			return el
+		}
+
+		hashable, ok := object.ToHashable(el)
+		if !ok {
+			return newError("unusable as set element: %s", el.Type())
+		}
+		set.Add(hashable)
+	}
+
+	return set
+}
+
+// x in s, whether x is an element of a set or array, or a key of a hash
+func evalInExpression(left, right object.Object, rt *object.Runtime) object.Object {
+	switch right := right.(type) {
+	case *object.Set:
+		el, ok := object.ToHashable(left)
+		return nativeBoolToBooleanObject(ok && right.Has(el))
+	case *object.Hash:
+		key, ok := object.ToHashable(left)
+		if !ok {
+			return FALSE
+		}
+		_, ok = right.Get(key)
+		return nativeBoolToBooleanObject(ok)
+	case *object.Array:
+		for _, el := range right.Elements {
+			equal, err := valuesEqual(left, el, rt)
+			if err != nil {
+				return err
+			}
+			if equal {
+				return TRUE
+			}
+		}
+		return FALSE
+	default:
+		return newError("unknown operator: %s in %s", left.Type(), right.Type())
+	}
+}
+
+// a | b union, a & b intersection and a - b difference of sets
+// the result keeps the order of a, followed by new elements of b for unions
+func evalSetInfixExpression(operator string, left, right object.Object, rt *object.Runtime) object.Object {
+	a := left.(*object.Set)
+	b := right.(*object.Set)
+
+	// elements of a, and for unions those of b, that end up in the result
+	var keepA, keepB func(el object.Hashable) bool
+	switch operator {
+	case "|":
+		keepA = func(object.Hashable) bool { return true }
+		keepB = func(el object.Hashable) bool { return !a.Has(el) }
+	case "&":
+		keepA = b.Has
+	case "-":
+		keepA = func(el object.Hashable) bool { return !b.Has(el) }
+	case "==":
+		return nativeBoolToBooleanObject(object.Equal(a, b))
+	case "!=":
+		return nativeBoolToBooleanObject(!object.Equal(a, b))
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	kept := keptElements(a, keepA)
+	if keepB != nil {
+		kept = append(kept, keptElements(b, keepB)...)
+	}
+
+	if err := rt.Alloc(object.HashSize(len(kept))); err != nil {
+		return err
+	}
+	result := object.NewSet(len(kept))
+	for _, el := range kept {
+		result.Add(el)
+	}
+	return result
+}
+
+// elements of set that keep holds for, in order
+func keptElements(set *object.Set, keep func(el object.Hashable) bool) []object.Hashable {
+	kept := []object.Hashable{}
+	for _, el := range set.Elements() {
+		if keep(el.(object.Hashable)) {
+			kept = append(kept, el.(object.Hashable))
+		}
+	}
+	return kept
+}
+
+// set(arr?): set of the elements of arr, an empty set without it
+func builtinSet(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	if len(args) == 0 {
+		return object.NewSet(0)
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `set` must be ARRAY, got %s", args[0].Type())
+	}
+
+	if err := rt.Alloc(object.HashSize(len(arr.Elements))); err != nil {
+		return err
+	}
+	set := object.NewSet(len(arr.Elements))
+	for _, el := range arr.Elements {
+		hashable, ok := object.ToHashable(el)
+		if !ok {
+			return newError("unusable as set element: %s", el.Type())
+		}
+		set.Add(hashable)
+	}
+
+	return set
+}
+
+// add(s, el): copy of s with el added at the end
+func builtinAdd(rt *object.Runtime, args ...object.Object) object.Object {
+	set, el, err := setAndElement("add", args)
+	if err != nil {
+		return err
+	}
+
+	if err := rt.Alloc(object.HashSize(set.Len() + 1)); err != nil {
+		return err
+	}
+	result := object.NewSet(set.Len() + 1)
+	for _, existing := range set.Elements() {
+		result.Add(existing.(object.Hashable))
+	}
+	result.Add(el)
+
+	return result
+}
+
+// remove(s, el): copy of s without el
+func builtinRemove(rt *object.Runtime, args ...object.Object) object.Object {
+	set, el, err := setAndElement("remove", args)
+	if err != nil {
+		return err
+	}
+
+	if err := rt.Alloc(object.HashSize(set.Len())); err != nil {
+		return err
+	}
+	result := object.NewSet(set.Len())
+	for _, existing := range set.Elements() {
+		if !object.KeysEqual(existing.(object.Hashable), el) {
+			result.Add(existing.(object.Hashable))
+		}
+	}
+
+	return result
+}
+
+// to_array(s): array of the elements of s in insertion order
+func builtinToArray(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	set, ok := args[0].(*object.Set)
+	if !ok {
+		return newError("argument to `to_array` must be SET, got %s", args[0].Type())
+	}
+
+	if err := rt.Alloc(object.ArraySize(set.Len())); err != nil {
+		return err
+	}
+	return &object.Array{Elements: set.Elements()}
+}
+
+// check the arguments of builtins called as name(set, el)
+func setAndElement(name string, args []object.Object) (*object.Set, object.Hashable, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	set, ok := args[0].(*object.Set)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be SET, got %s", name, args[0].Type())
+	}
+	el, ok := object.ToHashable(args[1])
+	if !ok {
+		return nil, nil, newError("unusable as set element: %s", args[1].Type())
+	}
+	return set, el, nil
+}
@@ -0,0 +1,30 @@
+package evaluator
+
+import (
+	"pika/object"
+	"pika/stdlib"
+)
+
+// evaluate the standard library into the prelude of the runtime of env
+// making its functions visible to the program run in env and every module it imports
+// names bound by programs shadow the standard library like they shadow builtins
+func LoadStdlib(env *object.Environment) *object.Error {
+	rt := env.Runtime()
+	if rt.Prelude == nil {
+		rt.Prelude = object.NewEnvironmentWithRuntime(rt)
+	}
+
+	for _, source := range stdlib.Sources() {
+		program, err := parseSource(source.Name, source.Code)
+		if err != nil {
+			return err
+		}
+
+		result := Eval(program, rt.Prelude)
+		if errObj, ok := result.(*object.Error); ok {
+			return errObj
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package evaluator
+
+import (
+	"math"
+	"pika/object"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtins working on strings
+// positions and lengths count characters (unicode code points), not bytes
+func init() {
+	builtins["split"] = &object.Builtin{Fn: builtinSplit}
+	builtins["join"] = &object.Builtin{Fn: builtinJoin}
+	builtins["trim"] = &object.Builtin{Fn: builtinTrim}
+	builtins["upper"] = &object.Builtin{Fn: builtinUpper}
+	builtins["lower"] = &object.Builtin{Fn: builtinLower}
+	builtins["replace"] = &object.Builtin{Fn: builtinReplace}
+	builtins["contains"] = &object.Builtin{Fn: builtinContains}
+	builtins["starts_with"] = &object.Builtin{Fn: builtinStartsWith}
+	builtins["ends_with"] = &object.Builtin{Fn: builtinEndsWith}
+	builtins["index_of"] = &object.Builtin{Fn: builtinIndexOf}
+	builtins["substr"] = &object.Builtin{Fn: builtinSubstr}
+	builtins["chars"] = &object.Builtin{Fn: builtinChars}
+	builtins["repeat"] = &object.Builtin{Fn: builtinRepeat}
+	builtins["pad_left"] = &object.Builtin{Fn: builtinPadLeft}
+	builtins["pad_right"] = &object.Builtin{Fn: builtinPadRight}
+}
+
+// split(s, sep): array of the parts of s between each sep
+// an empty sep splits s into its characters
+func builtinSplit(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("split", args, 2)
+	if err != nil {
+		return err
+	}
+	return newStringArray(rt, strings.Split(strs[0], strs[1]))
+}
+
+// join(arr, sep): the strings in arr with sep between them
+func builtinJoin(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to `join` must be ARRAY, got %s", args[0].Type())
+	}
+	sep, ok := args[1].(*object.String)
+	if !ok {
+		return newError("second argument to `join` must be STRING, got %s", args[1].Type())
+	}
+
+	parts := make([]string, len(arr.Elements))
+	for i, el := range arr.Elements {
+		str, ok := el.(*object.String)
+		if !ok {
+			return newError("`join` can only join strings, got %s", el.Type())
+		}
+		parts[i] = str.Value
+	}
+
+	return newString(rt, strings.Join(parts, sep.Value))
+}
+
+// trim(s): s without leading and trailing white space
+func builtinTrim(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("trim", args, 1)
+	if err != nil {
+		return err
+	}
+	return newString(rt, strings.TrimSpace(strs[0]))
+}
+
+func builtinUpper(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("upper", args, 1)
+	if err != nil {
+		return err
+	}
+	return newString(rt, strings.ToUpper(strs[0]))
+}
+
+func builtinLower(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("lower", args, 1)
+	if err != nil {
+		return err
+	}
+	return newString(rt, strings.ToLower(strs[0]))
+}
+
+// replace(s, old, new): s with every occurrence of old replaced by new
+func builtinReplace(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("replace", args, 3)
+	if err != nil {
+		return err
+	}
+	return newString(rt, strings.ReplaceAll(strs[0], strs[1], strs[2]))
+}
+
+func builtinContains(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("contains", args, 2)
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(strings.Contains(strs[0], strs[1]))
+}
+
+func builtinStartsWith(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("starts_with", args, 2)
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(strings.HasPrefix(strs[0], strs[1]))
+}
+
+func builtinEndsWith(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("ends_with", args, 2)
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(strings.HasSuffix(strs[0], strs[1]))
+}
+
+// index_of(s, sub): position of the first sub in s, -1 if there is none
+func builtinIndexOf(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("index_of", args, 2)
+	if err != nil {
+		return err
+	}
+
+	i := strings.Index(strs[0], strs[1])
+	if i < 0 {
+		return &object.Integer{Value: -1}
+	}
+	return &object.Integer{Value: int64(utf8.RuneCountInString(strs[0][:i]))}
+}
+
+// substr(s, start, length?): the characters of s from start on, at most length of them
+// start and length are clamped to the string so out of range values give shorter results
+func builtinSubstr(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to `substr` must be STRING, got %s", args[0].Type())
+	}
+	runes := []rune(str.Value)
+
+	start, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to `substr` must be INTEGER, got %s", args[1].Type())
+	}
+	from := clamp(start.Value, 0, int64(len(runes)))
+	to := int64(len(runes))
+	if len(args) == 3 {
+		length, ok := args[2].(*object.Integer)
+		if !ok {
+			return newError("third argument to `substr` must be INTEGER, got %s", args[2].Type())
+		}
+		// clamp the length first so from+length can't overflow
+		to = from + clamp(length.Value, 0, to-from)
+	}
+
+	return newString(rt, string(runes[from:to]))
+}
+
+// chars(s): array of the characters of s
+func builtinChars(rt *object.Runtime, args ...object.Object) object.Object {
+	strs, err := stringArguments("chars", args, 1)
+	if err != nil {
+		return err
+	}
+	return newStringArray(rt, strings.Split(strs[0], ""))
+}
+
+// repeat(s, n): s repeated n times
+func builtinRepeat(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to `repeat` must be STRING, got %s", args[0].Type())
+	}
+	count, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to `repeat` must be INTEGER, got %s", args[1].Type())
+	}
+	if count.Value < 0 {
+		return newError("`repeat` count must not be negative, got %d", count.Value)
+	}
+
+	if len(str.Value) > 0 && count.Value > math.MaxInt32/int64(len(str.Value)) {
+		return newError("`repeat` result is too large")
+	}
+
+	// account before building so a huge count fails on the limit instead of exhausting memory
+	if err := rt.Alloc(object.StringSize(len(str.Value) * int(count.Value))); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+}
+
+// pad_left(s, width, fill?): s with fill put in front of it until it is width characters long
+func builtinPadLeft(rt *object.Runtime, args ...object.Object) object.Object {
+	return pad("pad_left", rt, args, true)
+}
+
+// pad_right(s, width, fill?): s with fill put after it until it is width characters long
+func builtinPadRight(rt *object.Runtime, args ...object.Object) object.Object {
+	return pad("pad_right", rt, args, false)
+}
+
+// fill defaults to a space and must be a single character
+func pad(name string, rt *object.Runtime, args []object.Object, left bool) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	width, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to `%s` must be INTEGER, got %s", name, args[1].Type())
+	}
+	fill := " "
+	if len(args) == 3 {
+		f, ok := args[2].(*object.String)
+		if !ok || utf8.RuneCountInString(f.Value) != 1 {
+			return newError("third argument to `%s` must be a single character, got %s", name, args[2].Inspect())
+		}
+		fill = f.Value
+	}
+
+	missing := width.Value - int64(utf8.RuneCountInString(str.Value))
+	if missing <= 0 {
+		return str
+	}
+	if missing > math.MaxInt32 {
+		return newError("`%s` result is too large", name)
+	}
+	if err := rt.Alloc(object.StringSize(len(str.Value) + len(fill)*int(missing))); err != nil {
+		return err
+	}
+	padding := strings.Repeat(fill, int(missing))
+	if left {
+		return &object.String{Value: padding + str.Value}
+	}
+	return &object.String{Value: str.Value + padding}
+}
+
+// check the arguments of builtins that take exactly n strings and unwrap them
+func stringArguments(name string, args []object.Object, n int) ([]string, *object.Error) {
+	if len(args) != n {
+		return nil, newError("wrong number of arguments. got=%d, want=%d", len(args), n)
+	}
+
+	strs := make([]string, n)
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			if n == 1 {
+				return nil, newError("argument to `%s` must be STRING, got %s", name, arg.Type())
+			}
+			return nil, newError("%s argument to `%s` must be STRING, got %s", ordinals[i], name, arg.Type())
+		}
+		strs[i] = str.Value
+	}
+
+	return strs, nil
+}
+
+var ordinals = []string{"first", "second", "third"}
+
+func newString(rt *object.Runtime, s string) object.Object {
+	if err := rt.Alloc(object.StringSize(len(s))); err != nil {
+		return err
+	}
+	return &object.String{Value: s}
+}
+
+func newStringArray(rt *object.Runtime, strs []string) object.Object {
+	size := object.ArraySize(len(strs))
+	for _, s := range strs {
+		size += object.StringSize(len(s))
+	}
+	if err := rt.Alloc(size); err != nil {
+		return err
+	}
+
+	elements := make([]object.Object, len(strs))
+	for i, s := range strs {
+		elements[i] = &object.String{Value: s}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func clamp(n, lo, hi int64) int64 {
+	return max(lo, min(n, hi))
+}
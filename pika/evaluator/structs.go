@@ -0,0 +1,63 @@
+package evaluator
+
+import (
+	"pika/ast"
+	"pika/object"
+	"strings"
+)
+
+// struct Point { x, y } binds Point to a struct type that builds instances when called
+func evalStructStatement(node *ast.StructStatement, env *object.Environment) object.Object {
+	fields := make([]string, len(node.Fields))
+	for i, f := range node.Fields {
+		fields[i] = f.Value
+	}
+
+	structType := &object.StructType{Name: node.Name.Value, Fields: fields}
+	if err := declare(node.Name.Value, structType, false, env); err != nil {
+		return err
+	}
+	return nil
+}
+
+// instance of a struct type from a value for each of its fields, in order
+func newStruct(structType *object.StructType, args []object.Object, rt *object.Runtime) object.Object {
+	if len(args) != len(structType.Fields) {
+		return newError("wrong number of arguments to %s. got=%d, want=%d (%s)",
+			structType.Name, len(args), len(structType.Fields), strings.Join(structType.Fields, ", "))
+	}
+
+	if err := rt.Alloc(object.ArraySize(len(args))); err != nil {
+		return err
+	}
+	values := make([]object.Object, len(args))
+	copy(values, args)
+
+	return &object.Struct{Of: structType, Values: values}
+}
+
+// a.name looks up a field of a struct, a string key of a hash, an export of a module,
+// a field or method of an instance or a variant of an enum
+// unlike hashes, structs only have the fields they were declared with
+func evalMemberExpression(left object.Object, name *object.String) object.Object {
+	switch left := left.(type) {
+	case *object.Struct:
+		i, ok := left.Of.Field(name.Value)
+		if !ok {
+			return newError("%s has no field %s", left.Of.Name, name.Value)
+		}
+		return left.Values[i]
+	case *object.Hash:
+		return evalHashIndexExpression(left, name)
+	case *object.Module:
+		return evalModuleIndexExpression(left, name)
+	case *object.Instance:
+		return evalInstanceMember(left, name)
+	case *object.Super:
+		return evalSuperMember(left, name.Value)
+	case *object.Enum:
+		return evalEnumMember(left, name.Value)
+	default:
+		return newError("cannot access member %s of %s", name.Value, left.Type())
+	}
+}
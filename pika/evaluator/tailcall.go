@@ -0,0 +1,108 @@
+package evaluator
+
+import (
+	"pika/ast"
+	"pika/object"
+)
+
+// a call in tail position of a function body that has been evaluated up to,
+// but not including, applying the function
+// it only ever travels from evalTailBlock back to applyFunction
+type tailCall struct {
+	fn   object.Object
+	args []object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call" }
+
+// evaluate the statements of a function body like evalBlockStatement but
+// hand back calls in tail position as a *tailCall instead of applying them
+// a call is in tail position when it is the value of a return statement or,
+// when tail is set, the last expression of the block
+// if and ternary expressions pass tail position on to their branches
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
+	var result object.Object
+
+	if err := hoistFunctions(block.Statements, env); err != nil {
+		return err
+	}
+
+	for i, statement := range block.Statements {
+		last := tail && i == len(block.Statements)-1
+		result = evalTailStatement(statement, env, last)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+			if _, ok := result.(*tailCall); ok {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+func evalTailStatement(statement ast.Statement, env *object.Environment, tail bool) object.Object {
+	switch statement := statement.(type) {
+	case *ast.ReturnStatement:
+		val := evalTailExpression(statement.ReturnValue, env, true)
+		if isError(val) {
+			return val
+		}
+		if _, ok := val.(*tailCall); ok {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+
+	case *ast.ExpressionStatement:
+		return evalTailExpression(statement.Expression, env, tail)
+	}
+
+	return Eval(statement, env)
+}
+
+func evalTailExpression(exp ast.Expression, env *object.Environment, tail bool) object.Object {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		return evalCallExpression(exp, env, tail)
+
+	case *ast.IfExpression:
+		// branches are walked even when the if is not in tail position
+		// since a return statement inside them still is
+		condition := Eval(exp.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return evalTailBlock(exp.Consequence, newBlockEnvironment(env), tail)
+		} else if exp.Alternative != nil {
+			return evalTailBlock(exp.Alternative, newBlockEnvironment(env), tail)
+		} else {
+			return NULL
+		}
+
+	case *ast.TernaryExpression:
+		condition := Eval(exp.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return evalTailExpression(exp.Consequence, env, tail)
+		}
+		return evalTailExpression(exp.Alternative, env, tail)
+
+	case *ast.MatchExpression:
+		arm, armEnv, err := evalMatchArm(exp, env)
+		if err != nil {
+			return err
+		}
+		return evalTailBlock(arm.Body, armEnv, tail)
+	}
+
+	return Eval(exp, env)
+}
@@ -42,6 +42,10 @@ func (l *Lexer) NextToken() token.Token {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -54,7 +58,7 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.withAssign(token.PLUS, token.PLUS_ASSIGN)
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -64,11 +68,11 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.withAssign(token.MINUS, token.MINUS_ASSIGN)
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.withAssign(token.SLASH, token.SLASH_ASSIGN)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.withAssign(token.ASTERISK, token.ASTERISK_ASSIGN)
 	case '<':
 		tok = newToken(token.LT, l.ch)
 	case '>':
@@ -86,6 +90,50 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Literal = l.readString()
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '?':
+		// ?? ?. and ?[ are each a single token, anything else starts a ternary
+		// ?[ only indexes when the ? is right after the operand, so c ?[1] : [2] is a ternary
+		switch l.peekChar() {
+		case '?':
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.COALESCE, Literal: string(ch) + string(l.ch)}
+		case '.':
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.OPTIONAL_DOT, Literal: string(ch) + string(l.ch)}
+		case '[':
+			if l.afterWhitespace() {
+				tok = newToken(token.QUESTION, l.ch)
+				break
+			}
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.OPTIONAL_LBRACKET, Literal: string(ch) + string(l.ch)}
+		default:
+			tok = newToken(token.QUESTION, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.PIPE, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.BAR, l.ch)
+		}
+	case '.':
+		tok = newToken(token.DOT, l.ch)
+	case '&':
+		tok = newToken(token.AMPERSAND, l.ch)
+	case '#':
+		// # only starts set literals
+		if l.peekChar() == '{' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.SET_LBRACE, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -114,6 +162,16 @@ func newToken(tokenType token.TokenType, ch byte) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
+// operator token for the current char, or its compound assignment when followed by =
+func (l *Lexer) withAssign(op, assign token.TokenType) token.Token {
+	if l.peekChar() == '=' {
+		ch := l.ch
+		l.readChar()
+		return token.Token{Type: assign, Literal: string(ch) + string(l.ch)}
+	}
+	return newToken(op, l.ch)
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for l.isLetter(l.ch) {
@@ -126,10 +184,32 @@ func (l *Lexer) isLetter(ch byte) bool {
 	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
 }
 
-// skip any white spaces in the input
+// skip any white spaces and // comments in the input
 func (l *Lexer) skipWhitespace() {
-	if l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// whether the current char is at the start of the input or follows white space
+func (l *Lexer) afterWhitespace() bool {
+	if l.position == 0 {
+		return true
+	}
+	switch l.input[l.position-1] {
+	case ' ', '\t', '\n', '\r':
+		return true
+	default:
+		return false
 	}
 }
 
@@ -21,7 +21,21 @@ return true;
 return false;
 }
 10 == 10;
-10 != 9;`
+10 != 9;
+null ?? a?.b?[0];
+a ? b : c |> f;
+(x) => x;
+const y = 1;
+import "lib/math.pika" as m;
+export fn // comments run to the end of the line
+// and are skipped like whitespace
+10 / 2;
+#{1} | a & b;
+x in s;
+class B extends A {}
+n += 1; n -= 1; n *= 2; n /= 2;
+enum E { A(x) } match e { _ => 1 }
+c ?[1] : [2];`
 
 	tests := []struct {
 		expectedType    token.TokenType
@@ -100,6 +114,104 @@ return false;
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.NULL, "null"},
+		{token.COALESCE, "??"},
+		{token.IDENT, "a"},
+		{token.OPTIONAL_DOT, "?."},
+		{token.IDENT, "b"},
+		{token.OPTIONAL_LBRACKET, "?["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "b"},
+		{token.COLON, ":"},
+		{token.IDENT, "c"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.CONST, "const"},
+		{token.IDENT, "y"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IMPORT, "import"},
+		{token.STRING, "lib/math.pika"},
+		{token.AS, "as"},
+		{token.IDENT, "m"},
+		{token.SEMICOLON, ";"},
+		{token.EXPORT, "export"},
+		{token.FUNCTION, "fn"},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.SET_LBRACE, "#{"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.BAR, "|"},
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.IN, "in"},
+		{token.IDENT, "s"},
+		{token.SEMICOLON, ";"},
+		{token.CLASS, "class"},
+		{token.IDENT, "B"},
+		{token.EXTENDS, "extends"},
+		{token.IDENT, "A"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "n"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "n"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "n"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "n"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.ENUM, "enum"},
+		{token.IDENT, "E"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "A"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.RBRACE, "}"},
+		{token.MATCH, "match"},
+		{token.IDENT, "e"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "c"},
+		{token.QUESTION, "?"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.RBRACKET, "]"},
+		{token.COLON, ":"},
+		{token.LBRACKET, "["},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 
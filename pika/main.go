@@ -1,13 +1,53 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
+	"pika/evaluator"
+	"pika/object"
 	"pika/repl"
 )
 
 func main() {
+	legacyBlockScope := flag.Bool("legacy-block-scope", false, "evaluate if/else blocks in the enclosing scope like older versions did")
+	noStdlib := flag.Bool("no-stdlib", false, "don't load the standard library written in pika")
+	warnRedeclare := flag.Bool("warn-redeclare", false, "warn when a let redeclares a name in the same scope")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file.pika]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "without a file an interactive session is started\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "modules are also searched for in the directories listed in PIKA_PATH\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	env := object.NewEnvironment()
+	rt := env.Runtime()
+	rt.LegacyBlockScope = *legacyBlockScope
+	if *warnRedeclare {
+		rt.Warnings = os.Stderr
+	}
+	rt.ModulePath = filepath.SplitList(os.Getenv("PIKA_PATH"))
+
+	if !*noStdlib {
+		if errObj := evaluator.LoadStdlib(env); errObj != nil {
+			fmt.Fprintln(os.Stderr, errObj.Inspect())
+			os.Exit(1)
+		}
+	}
+
+	// run a file if one is given
+	if flag.NArg() > 0 {
+		result := evaluator.EvalFile(flag.Arg(0), env)
+		if errObj, ok := result.(*object.Error); ok {
+			fmt.Fprintln(os.Stderr, errObj.Inspect())
+			os.Exit(1)
+		}
+		return
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -15,5 +55,5 @@ func main() {
 	fmt.Printf(repl.PIKA)
 	fmt.Printf("Hello %s! This is the PIKA programming language!\n", user.Username)
 	fmt.Printf("Feel free to type in commands!\n\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, env)
 }
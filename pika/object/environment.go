@@ -3,22 +3,30 @@ package object
 // wrapper around map for language runtime
 // everytime a variable is assigned it will be stored in the language runtime
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store   map[string]Object
+	consts  map[string]bool // names in store bound with SetConst
+	outer   *Environment
+	runtime *Runtime
 }
 
 func NewEnvironment() *Environment {
+	return NewEnvironmentWithRuntime(NewRuntime())
+}
+
+// new top level environment sharing an existing runtime
+// such as the environment a module is evaluated in
+func NewEnvironmentWithRuntime(rt *Runtime) *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{store: s, outer: nil, runtime: rt}
 }
 
 // outer makes a chain of envionments: nested scopes
 // when Get doesn't find something it moves to the outer scope
 // this allows functions to have lexical scoping
+// enclosed environments share the runtime of the outer one
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
-	return env
+	s := make(map[string]Object)
+	return &Environment{store: s, outer: outer, runtime: outer.runtime}
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -33,3 +41,39 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// bind name to a value that must not be rebound in this scope
+func (e *Environment) SetConst(name string, val Object) Object {
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.consts[name] = true
+	e.store[name] = val
+	return val
+}
+
+// whether name is bound in this scope, bindings of outer scopes don't count
+func (e *Environment) Declared(name string) bool {
+	_, ok := e.store[name]
+	return ok
+}
+
+// innermost environment name is bound in, nil when no scope binds it
+func (e *Environment) Resolve(name string) *Environment {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env
+		}
+	}
+	return nil
+}
+
+// whether name is bound in this scope with SetConst
+func (e *Environment) IsConst(name string) bool {
+	return e.consts[name]
+}
+
+// runtime shared by this environment and all environments enclosed by it
+func (e *Environment) Runtime() *Runtime {
+	return e.runtime
+}
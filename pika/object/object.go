@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"path/filepath"
 	"pika/ast"
 	"strings"
 )
@@ -26,6 +27,17 @@ const (
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	MODULE_OBJ       = "MODULE"
+	SET_OBJ          = "SET"
+	STRUCT_TYPE_OBJ  = "STRUCT_TYPE"
+	STRUCT_OBJ       = "STRUCT"
+	CLASS_OBJ        = "CLASS"
+	INSTANCE_OBJ     = "INSTANCE"
+	METHOD_OBJ       = "METHOD"
+	SUPER_OBJ        = "SUPER"
+	ENUM_OBJ         = "ENUM"
+	VARIANT_OBJ      = "VARIANT"
+	ENUM_VALUE_OBJ   = "ENUM_VALUE"
 )
 
 // whenever we encounter an integer in source code
@@ -66,8 +78,21 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Error struct {
 	Message string
+	// set when evaluation was aborted by a limit of the runtime rather than
+	// by a mistake in the program, so embedders can tell the two apart
+	Kind ErrorKind
 }
 
+type ErrorKind string
+
+const (
+	STACK_OVERFLOW ErrorKind = "STACK_OVERFLOW"
+	STEP_LIMIT     ErrorKind = "STEP_LIMIT"
+	TIMEOUT        ErrorKind = "TIMEOUT"
+	CANCELED       ErrorKind = "CANCELED"
+	MEMORY_LIMIT   ErrorKind = "MEMORY_LIMIT"
+)
+
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "Error: " + e.Message }
 
@@ -77,6 +102,7 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string // empty for anonymous functions
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -89,6 +115,9 @@ func (f *Function) Inspect() string {
 	}
 
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -101,13 +130,18 @@ func (f *Function) Inspect() string {
 // string object
 type String struct {
 	Value string
+
+	// strings never change so their HashKey is computed once and kept
+	hashKey HashKey
+	hashed  bool
 }
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
 
 // builtin functions
-type BuiltinFunction func(args ...Object) Object
+// they get the runtime of the caller so they can account for what they allocate
+type BuiltinFunction func(rt *Runtime, args ...Object) Object
 
 type Builtin struct {
 	Fn BuiltinFunction
@@ -119,6 +153,10 @@ func (b *Builtin) Inspect() string  { return "builtin function" }
 // array object
 type Array struct {
 	Elements []Object
+
+	// arrays never change so their HashKey is computed once and kept
+	hashKey HashKey
+	hashed  bool
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
@@ -162,12 +200,149 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
-// method to generate hash of string objects (using FNV hash function)
-func (s *String) HashKey() HashKey {
+// function used to hash strings, FNV by default
+// hashes check keys for equality so a hasher only affects speed, never results,
+// which is what lets tests swap in one that makes every string collide
+// strings cache their key, so swap it before any string has been hashed
+var StringHasher = func(s string) uint64 {
 	h := fnv.New64a()
-	h.Write([]byte(s.Value))
+	h.Write([]byte(s))
+
+	return h.Sum64()
+}
 
-	return HashKey{Type: s.Type(), Value: h.Sum64()}
+// method to generate hash of string objects (using StringHasher)
+// only the first call hashes the string, later ones return the cached key
+func (s *String) HashKey() HashKey {
+	if !s.hashed {
+		s.hashKey = HashKey{Type: s.Type(), Value: StringHasher(s.Value)}
+		s.hashed = true
+	}
+	return s.hashKey
+}
+
+// method to generate hash of array objects by combining the keys of their elements
+// in order, so arrays with equal elements get the same key
+// only meaningful for arrays that ToHashable accepts, other elements hash to 0
+func (a *Array) HashKey() HashKey {
+	if a.hashed {
+		return a.hashKey
+	}
+
+	a.hashKey = HashKey{Type: a.Type(), Value: hashElements(fnvOffset, a.Elements)}
+	a.hashed = true
+	return a.hashKey
+}
+
+const fnvOffset, fnvPrime = 14695981039346656037, 1099511628211
+
+// FNV-1a over the type and value of each element key, starting from h
+func hashElements(h uint64, elements []Object) uint64 {
+	for _, el := range elements {
+		var key HashKey
+		if hashable, ok := el.(Hashable); ok {
+			key = hashable.HashKey()
+		}
+		h = (h ^ StringHasher(string(key.Type))) * fnvPrime
+		h = (h ^ key.Value) * fnvPrime
+	}
+	return h
+}
+
+// obj as a hash key if it can be one
+// arrays and enum values can only be keys when every element can, checked all the way down
+func ToHashable(obj Object) (Hashable, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		for _, el := range obj.Elements {
+			if _, ok := ToHashable(el); !ok {
+				return nil, false
+			}
+		}
+		return obj, true
+	case *EnumValue:
+		for _, value := range obj.Values {
+			if _, ok := ToHashable(value); !ok {
+				return nil, false
+			}
+		}
+		return obj, true
+	case Hashable:
+		return obj, true
+	default:
+		return nil, false
+	}
+}
+
+// whether a and b are the same key
+// different keys can have the same HashKey so lookups compare the keys themselves
+func KeysEqual(a, b Hashable) bool {
+	return Equal(a, b)
+}
+
+// whether a and b are equal values
+// booleans, integers and strings are compared by value, arrays element by
+// element, sets by their elements in any order, structs and enum values
+// field by field and everything else by identity
+func Equal(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Array:
+		other := b.(*Array)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i, el := range a.Elements {
+			if !Equal(el, other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		other := b.(*Struct)
+		if a.Of != other.Of {
+			return false
+		}
+		for i, value := range a.Values {
+			if !Equal(value, other.Values[i]) {
+				return false
+			}
+		}
+		return true
+	case *EnumValue:
+		other := b.(*EnumValue)
+		if a.Variant != other.Variant {
+			return false
+		}
+		for i, value := range a.Values {
+			if !Equal(value, other.Values[i]) {
+				return false
+			}
+		}
+		return true
+	case *Set:
+		other := b.(*Set)
+		if a.Len() != other.Len() {
+			return false
+		}
+		for _, pair := range a.elements.Pairs() {
+			if !other.Has(pair.Key.(Hashable)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
 }
 
 type HashPair struct {
@@ -175,20 +350,72 @@ type HashPair struct {
 	Value Object
 }
 
+// hashmap object
+// pairs are kept in the order their keys were first added, next to an index
+// from the hash of each key to the positions of the keys with that hash
+// keys sharing a hash are told apart with KeysEqual, so collisions only cost time
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs []HashPair
+	index map[HashKey][]int
 }
 
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
+// empty hash with room for size pairs
+func NewHash(size int) *Hash {
+	return &Hash{pairs: make([]HashPair, 0, size), index: make(map[HashKey][]int, size)}
+}
+
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
+
+// number of pairs in the hash
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// pairs of the hash in insertion order, the slice must not be modified
+func (h *Hash) Pairs() []HashPair { return h.pairs }
+
+// value stored for key
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	i, ok := h.find(key, key.HashKey())
+	if !ok {
+		return nil, false
+	}
+	return h.pairs[i].Value, true
+}
+
+// store value for key, a key already in the hash keeps its position
+func (h *Hash) Set(key Hashable, value Object) {
+	if h.index == nil {
+		h.index = make(map[HashKey][]int)
+	}
+
+	hashKey := key.HashKey()
+	if i, ok := h.find(key, hashKey); ok {
+		h.pairs[i].Value = value
+		return
+	}
+	h.index[hashKey] = append(h.index[hashKey], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+// position of key in pairs, looked up in the bucket of its hash
+func (h *Hash) find(key Hashable, hashKey HashKey) (int, bool) {
+	for _, i := range h.index[hashKey] {
+		if KeysEqual(h.pairs[i].Key.(Hashable), key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -198,3 +425,278 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// set object, a hash from each element to itself
+// so elements are unique by KeysEqual and kept in insertion order
+type Set struct {
+	elements Hash
+}
+
+// empty set with room for size elements
+func NewSet(size int) *Set {
+	return &Set{elements: *NewHash(size)}
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+
+// number of elements in the set
+func (s *Set) Len() int { return s.elements.Len() }
+
+// whether el is in the set
+func (s *Set) Has(el Hashable) bool {
+	_, ok := s.elements.Get(el)
+	return ok
+}
+
+// put el in the set, an element already in it keeps its position
+func (s *Set) Add(el Hashable) { s.elements.Set(el, el) }
+
+// elements of the set in insertion order
+func (s *Set) Elements() []Object {
+	elements := make([]Object, 0, s.Len())
+	for _, pair := range s.elements.Pairs() {
+		elements = append(elements, pair.Key)
+	}
+	return elements
+}
+
+func (s *Set) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, pair := range s.elements.Pairs() {
+		elements = append(elements, pair.Key.Inspect())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// type declared with struct Name { fields }
+// calling it with a value for every field builds an instance
+type StructType struct {
+	Name   string
+	Fields []string
+}
+
+func (st *StructType) Type() ObjectType { return STRUCT_TYPE_OBJ }
+func (st *StructType) Inspect() string {
+	if len(st.Fields) == 0 {
+		return "struct " + st.Name + " {}"
+	}
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+// position of a field in the values of instances
+func (st *StructType) Field(name string) (int, bool) {
+	for i, field := range st.Fields {
+		if field == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// instance of a struct type, its values are in the order of the fields
+type Struct struct {
+	Of     *StructType
+	Values []Object
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
+func (s *Struct) Inspect() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for i, field := range s.Of.Fields {
+		fields = append(fields, field+": "+s.Values[i].Inspect())
+	}
+
+	out.WriteString(s.Of.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// class declared with the class statement, calling it makes an instance
+type Class struct {
+	Name    string
+	Parent  *Class // nil when the class doesn't extend another one
+	Methods map[string]*Function
+}
+
+func (c *Class) Type() ObjectType { return CLASS_OBJ }
+func (c *Class) Inspect() string {
+	if c.Parent != nil {
+		return "class " + c.Name + " extends " + c.Parent.Name
+	}
+	return "class " + c.Name
+}
+
+// method name of the class or its closest ancestor having one, and the class defining it
+func (c *Class) Method(name string) (*Function, *Class, bool) {
+	for class := c; class != nil; class = class.Parent {
+		if fn, ok := class.Methods[name]; ok {
+			return fn, class, true
+		}
+	}
+	return nil, nil, false
+}
+
+// instance of a class
+// unlike structs its fields are mutable and added by assigning to them
+type Instance struct {
+	Class      *Class
+	fields     *Hash
+	inspecting bool // set while Inspect prints the fields
+}
+
+func NewInstance(class *Class) *Instance {
+	return &Instance{Class: class, fields: NewHash(0)}
+}
+
+func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
+
+// an instance reached again while printing its own fields is printed as <cycle>
+// instances are the only values that can refer back to themselves
+func (i *Instance) Inspect() string {
+	if i.inspecting {
+		return "<cycle>"
+	}
+	i.inspecting = true
+	defer func() { i.inspecting = false }()
+
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, pair := range i.fields.Pairs() {
+		fields = append(fields, pair.Key.(*String).Value+": "+pair.Value.Inspect())
+	}
+
+	out.WriteString(i.Class.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// fields are looked up by a string object so callers can reuse its cached hash key
+func (i *Instance) Field(name *String) (Object, bool) {
+	return i.fields.Get(name)
+}
+
+// set a field, fields keep the position they were first set at
+func (i *Instance) SetField(name *String, val Object) {
+	i.fields.Set(name, val)
+}
+
+// method looked up on an instance, calling it binds self to the instance
+// Class is the class defining the method, the one super refers to is its parent
+// the method is named after it as in Counter.inc
+type BoundMethod struct {
+	Self   *Instance
+	Method *Function
+	Class  *Class
+}
+
+func (bm *BoundMethod) Type() ObjectType { return METHOD_OBJ }
+func (bm *BoundMethod) Inspect() string {
+	return "<method " + bm.Method.Name + ">"
+}
+
+// value of super in a method, its members are the methods of Class bound to Self
+type Super struct {
+	Self  *Instance
+	Class *Class
+}
+
+func (s *Super) Type() ObjectType { return SUPER_OBJ }
+func (s *Super) Inspect() string  { return "<super " + s.Class.Name + ">" }
+
+// enum declared with the enum statement, its variants are its members
+type Enum struct {
+	Name     string
+	Variants []*Variant
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	if len(e.Variants) == 0 {
+		return "enum " + e.Name + " {}"
+	}
+
+	variants := []string{}
+	for _, v := range e.Variants {
+		variants = append(variants, v.signature())
+	}
+	return "enum " + e.Name + " { " + strings.Join(variants, ", ") + " }"
+}
+
+func (e *Enum) Variant(name string) (*Variant, bool) {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// variant of an enum, a variant with fields builds values of the enum when called
+type Variant struct {
+	Enum   *Enum
+	Name   string
+	Fields []string
+}
+
+func (v *Variant) Type() ObjectType { return VARIANT_OBJ }
+func (v *Variant) Inspect() string  { return "<variant " + v.Enum.Name + "." + v.signature() + ">" }
+
+// name of the variant followed by its fields, if it has any
+func (v *Variant) signature() string {
+	if len(v.Fields) == 0 {
+		return v.Name
+	}
+	return v.Name + "(" + strings.Join(v.Fields, ", ") + ")"
+}
+
+// value of an enum, one of its variants with a value for each of the fields of the variant
+type EnumValue struct {
+	Variant *Variant
+	Values  []Object
+}
+
+func (ev *EnumValue) Type() ObjectType { return ENUM_VALUE_OBJ }
+func (ev *EnumValue) Inspect() string {
+	name := ev.Variant.Enum.Name + "." + ev.Variant.Name
+	if len(ev.Values) == 0 {
+		return name
+	}
+
+	values := []string{}
+	for _, value := range ev.Values {
+		values = append(values, value.Inspect())
+	}
+	return name + "(" + strings.Join(values, ", ") + ")"
+}
+
+// the variant is hashed by name, its values like the elements of an array
+func (ev *EnumValue) HashKey() HashKey {
+	h := (fnvOffset ^ StringHasher(ev.Variant.Enum.Name+"."+ev.Variant.Name)) * fnvPrime
+	return HashKey{Type: ev.Type(), Value: hashElements(h, ev.Values)}
+}
+
+// module object, the result of importing a .pika file
+// its exports are kept in a hash from their names to their values
+type Module struct {
+	Path    string // absolute path of the file
+	Exports *Hash
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "<module " + filepath.Base(m.Path) + ">" }
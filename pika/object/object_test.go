@@ -0,0 +1,171 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHashKeyCollisions(t *testing.T) {
+	// every string gets the same hash
+	defer func(hasher func(string) uint64) { StringHasher = hasher }(StringHasher)
+	StringHasher = func(string) uint64 { return 42 }
+
+	hash := NewHash(0)
+	for i := 0; i < 10; i++ {
+		hash.Set(&String{Value: fmt.Sprintf("key%d", i)}, &Integer{Value: int64(i)})
+	}
+	hash.Set(&Integer{Value: 42}, &String{Value: "integer"})
+	hash.Set(&String{Value: "key3"}, &Integer{Value: 33})
+
+	if hash.Len() != 11 {
+		t.Fatalf("colliding keys overwrote each other. got %d pairs, want 11", hash.Len())
+	}
+
+	for i := 0; i < 10; i++ {
+		expected := int64(i)
+		if i == 3 {
+			expected = 33
+		}
+
+		value, ok := hash.Get(&String{Value: fmt.Sprintf("key%d", i)})
+		if !ok {
+			t.Errorf("key%d not found", i)
+			continue
+		}
+		if value.(*Integer).Value != expected {
+			t.Errorf("wrong value for key%d. got %d, want %d", i, value.(*Integer).Value, expected)
+		}
+	}
+
+	if value, ok := hash.Get(&Integer{Value: 42}); !ok || value.(*String).Value != "integer" {
+		t.Errorf("wrong value for 42. got %v", value)
+	}
+	if _, ok := hash.Get(&String{Value: "missing"}); ok {
+		t.Errorf("found a key that was never set")
+	}
+
+	expected := "{key0: 0, key1: 1, key2: 2, key3: 33, key4: 4, key5: 5, key6: 6, key7: 7, key8: 8, key9: 9, 42: integer}"
+	if hash.Inspect() != expected {
+		t.Errorf("wrong Inspect output. got %q", hash.Inspect())
+	}
+}
+
+func TestKeysEqual(t *testing.T) {
+	tests := []struct {
+		a, b     Hashable
+		expected bool
+	}{
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&String{Value: "a"}, &String{Value: "b"}, false},
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &String{Value: "1"}, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Boolean{Value: true}, &Integer{Value: 1}, false},
+	}
+
+	for _, tt := range tests {
+		if KeysEqual(tt.a, tt.b) != tt.expected {
+			t.Errorf("KeysEqual(%s, %s) should be %t", tt.a.Inspect(), tt.b.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestStringHashKeyIsCached(t *testing.T) {
+	defer func(hasher func(string) uint64) { StringHasher = hasher }(StringHasher)
+	calls := 0
+	StringHasher = func(string) uint64 { calls++; return 7 }
+
+	s := &String{Value: "pika"}
+	first := s.HashKey()
+	second := s.HashKey()
+
+	if first != second {
+		t.Errorf("cached key differs. got %v and %v", first, second)
+	}
+	if calls != 1 {
+		t.Errorf("string hashed %d times, want 1", calls)
+	}
+	if (&String{Value: "pika"}).HashKey() != first {
+		t.Errorf("equal strings have different keys")
+	}
+}
+
+var benchmarkKey HashKey
+var benchmarkValue Object
+
+// a long key makes the cost of hashing it visible
+var benchmarkString = strings.Repeat("pika", 64)
+
+func BenchmarkStringHashKey(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		// a fresh string is hashed every time, like before keys were cached
+		benchmarkKey = (&String{Value: benchmarkString}).HashKey()
+	}
+}
+
+func BenchmarkStringHashKeyCached(b *testing.B) {
+	s := &String{Value: benchmarkString}
+	for i := 0; i < b.N; i++ {
+		benchmarkKey = s.HashKey()
+	}
+}
+
+func BenchmarkHashGet(b *testing.B) {
+	hash := NewHash(100)
+	keys := make([]*String, 100)
+	for i := range keys {
+		keys[i] = &String{Value: fmt.Sprintf("%s%d", benchmarkString, i)}
+		hash.Set(keys[i], &Integer{Value: int64(i)})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchmarkValue, _ = hash.Get(keys[i%len(keys)])
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	pair := func(a, b Object) *Array { return &Array{Elements: []Object{a, b}} }
+	one, two := &Integer{Value: 1}, &Integer{Value: 2}
+
+	if pair(one, two).HashKey() != pair(&Integer{Value: 1}, &Integer{Value: 2}).HashKey() {
+		t.Errorf("equal arrays have different keys")
+	}
+	if pair(one, two).HashKey() == pair(two, one).HashKey() {
+		t.Errorf("order of elements doesn't change the key")
+	}
+	if pair(one, two).HashKey() == pair(&String{Value: "1"}, two).HashKey() {
+		t.Errorf("type of elements doesn't change the key")
+	}
+	if (&Array{}).HashKey() == (&Array{Elements: []Object{&Array{}}}).HashKey() {
+		t.Errorf("nesting doesn't change the key")
+	}
+
+	if _, ok := ToHashable(pair(one, &Array{Elements: []Object{&Hash{}}})); ok {
+		t.Errorf("array holding a hash is usable as a key")
+	}
+	if !Equal(pair(one, pair(two, one)), pair(one, pair(two, one))) {
+		t.Errorf("nested arrays with equal elements are not equal")
+	}
+}
+
+func TestEnumValueHashKey(t *testing.T) {
+	result := &Enum{Name: "Result"}
+	ok := &Variant{Enum: result, Name: "Ok", Fields: []string{"v"}}
+	err := &Variant{Enum: result, Name: "Err", Fields: []string{"e"}}
+	one := &Integer{Value: 1}
+
+	if (&EnumValue{Variant: ok, Values: []Object{one}}).HashKey() != (&EnumValue{Variant: ok, Values: []Object{&Integer{Value: 1}}}).HashKey() {
+		t.Errorf("equal enum values have different keys")
+	}
+	if (&EnumValue{Variant: ok, Values: []Object{one}}).HashKey() == (&EnumValue{Variant: err, Values: []Object{one}}).HashKey() {
+		t.Errorf("variant doesn't change the key")
+	}
+	if Equal(&EnumValue{Variant: ok, Values: []Object{one}}, &EnumValue{Variant: err, Values: []Object{one}}) {
+		t.Errorf("values of different variants are equal")
+	}
+	if _, hashable := ToHashable(&EnumValue{Variant: ok, Values: []Object{&Hash{}}}); hashable {
+		t.Errorf("enum value holding a hash is usable as a key")
+	}
+}
@@ -0,0 +1,206 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// call depth allowed by a runtime unless configured otherwise
+// well below the depth at which the go stack of the evaluator runs out
+const DefaultMaxDepth = 10000
+
+// rough sizes in bytes of the parts values are built from
+// used to account for what a program allocates, see Runtime.Alloc
+const (
+	HeaderSize  = 16 // an object itself
+	ElementSize = 16 // one element of an array (an interface value)
+	PairSize    = 64 // one entry of a hash: its HashKey and HashPair
+)
+
+// approximate number of bytes taken by a string of n bytes
+func StringSize(n int) int64 { return HeaderSize + int64(n) }
+
+// approximate number of bytes taken by an array of n elements
+func ArraySize(n int) int64 { return HeaderSize + int64(n)*ElementSize }
+
+// approximate number of bytes taken by a hash of n pairs
+func HashSize(n int) int64 { return HeaderSize + int64(n)*PairSize }
+
+// how many steps are taken between checks of the context
+// checking on every step would make the context the bottleneck of evaluation
+const contextCheckInterval = 1024
+
+// interpreter wide state shared by an environment and every environment enclosed by it
+// this is where limits on the running program are configured and tracked
+type Runtime struct {
+	// maximum number of nested function calls, zero or less disables the check
+	MaxDepth int
+	// maximum number of bytes the program may allocate for strings, arrays
+	// and hashes over the life of the runtime, zero or less disables the check
+	MaxBytes int64
+	// evaluate the blocks of if expressions in the enclosing scope instead of
+	// a scope of their own, so let inside them rebinds outer variables
+	// this is how blocks used to behave and is only meant for old programs
+	LegacyBlockScope bool
+	// where warnings about questionable but valid code are written, such as
+	// a let redeclaring a name in the same scope, nil discards them
+	Warnings io.Writer
+	// directories searched for imported modules not found next to the importing file
+	ModulePath []string
+	// bindings visible to every program and module of the runtime such as the
+	// standard library, looked up when a name is not found in scope
+	Prelude *Environment
+
+	depth     int   // number of function calls currently being evaluated
+	allocated int64 // bytes accounted for with Alloc
+
+	// budget of the evaluation in progress, see SetBudget
+	ctx      context.Context
+	maxSteps int
+	steps    int
+	aborted  *Error // once a budget is exhausted every further step fails with this
+
+	modules   map[string]*Module // modules already imported by absolute path
+	importing []string           // files being evaluated, the innermost last
+}
+
+func NewRuntime() *Runtime {
+	return &Runtime{MaxDepth: DefaultMaxDepth, modules: make(map[string]*Module)}
+}
+
+// record entry into a function call
+// returns false without entering when the call would exceed MaxDepth
+func (rt *Runtime) EnterCall() bool {
+	if rt.MaxDepth > 0 && rt.depth >= rt.MaxDepth {
+		return false
+	}
+	rt.depth++
+	return true
+}
+
+// record return from a function call entered with EnterCall
+func (rt *Runtime) ExitCall() {
+	rt.depth--
+}
+
+// number of function calls currently being evaluated
+func (rt *Runtime) Depth() int {
+	return rt.depth
+}
+
+// account for n bytes about to be allocated by the program
+// returns the error to abort evaluation with when that would exceed MaxBytes,
+// in which case the allocation must not be made
+// the count is cumulative: it approximates how much the program allocated,
+// not how much of it is still in use
+// unlike an exhausted budget this doesn't stop later steps, code that
+// allocates nothing still runs and anything else keeps hitting the limit
+func (rt *Runtime) Alloc(n int64) *Error {
+	if rt.MaxBytes > 0 && rt.allocated+n > rt.MaxBytes {
+		return &Error{
+			Message: fmt.Sprintf("memory limit of %d bytes exceeded", rt.MaxBytes),
+			Kind:    MEMORY_LIMIT,
+		}
+	}
+	rt.allocated += n
+	return nil
+}
+
+// number of bytes accounted for with Alloc
+func (rt *Runtime) Allocated() int64 {
+	return rt.allocated
+}
+
+// write a warning about the program to Warnings
+func (rt *Runtime) Warn(format string, a ...interface{}) {
+	if rt.Warnings == nil {
+		return
+	}
+	fmt.Fprintf(rt.Warnings, "warning: "+format+"\n", a...)
+}
+
+// module imported from path before, if any
+func (rt *Runtime) Module(path string) (*Module, bool) {
+	module, ok := rt.modules[path]
+	return module, ok
+}
+
+// remember a module so later imports of path get the same one
+func (rt *Runtime) AddModule(module *Module) {
+	rt.modules[module.Path] = module
+}
+
+// record that the file at path starts being evaluated
+// returns false without entering when path is already being evaluated,
+// which means it imports itself through the files in between
+func (rt *Runtime) EnterFile(path string) bool {
+	for _, f := range rt.importing {
+		if f == path {
+			return false
+		}
+	}
+	rt.importing = append(rt.importing, path)
+	return true
+}
+
+// record that the file entered last with EnterFile is done
+func (rt *Runtime) ExitFile() {
+	rt.importing = rt.importing[:len(rt.importing)-1]
+}
+
+// the files being evaluated, from the outermost to the innermost
+// empty when evaluating code that doesn't come from a file like the repl input
+func (rt *Runtime) Files() []string {
+	return rt.importing
+}
+
+// start enforcing a budget on evaluation
+// evaluation stops once ctx is done or, if maxSteps is positive, after maxSteps steps
+// ctx may be nil to only limit the number of steps
+func (rt *Runtime) SetBudget(ctx context.Context, maxSteps int) {
+	rt.ctx = ctx
+	rt.maxSteps = maxSteps
+	rt.steps = 0
+	rt.aborted = nil
+}
+
+// stop enforcing the budget set with SetBudget
+func (rt *Runtime) ClearBudget() {
+	rt.SetBudget(nil, 0)
+}
+
+// number of steps taken since the budget was set
+func (rt *Runtime) Steps() int {
+	return rt.steps
+}
+
+// account for one step of evaluation, the evaluator takes one per node
+// returns the error to abort evaluation with once the budget is exhausted
+func (rt *Runtime) Step() *Error {
+	if rt.aborted != nil {
+		return rt.aborted
+	}
+
+	rt.steps++
+
+	if rt.maxSteps > 0 && rt.steps > rt.maxSteps {
+		rt.aborted = &Error{
+			Message: fmt.Sprintf("step limit of %d exceeded", rt.maxSteps),
+			Kind:    STEP_LIMIT,
+		}
+		return rt.aborted
+	}
+
+	if rt.ctx != nil && (rt.steps-1)%contextCheckInterval == 0 {
+		switch err := rt.ctx.Err(); {
+		case errors.Is(err, context.DeadlineExceeded):
+			rt.aborted = &Error{Message: "evaluation timed out", Kind: TIMEOUT}
+		case err != nil:
+			rt.aborted = &Error{Message: "evaluation canceled", Kind: CANCELED}
+		}
+	}
+
+	return rt.aborted
+}
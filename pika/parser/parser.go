@@ -38,6 +38,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.SET_LBRACE, p.parseSetLiteral)
+	p.registerPrefix(token.NULL, p.parseNull)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	// infix parse functions
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -51,6 +54,20 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.COALESCE, p.parseInfixExpression)
+	p.registerInfix(token.OPTIONAL_LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.OPTIONAL_DOT, p.parseMemberExpression)
+	p.registerInfix(token.QUESTION, p.parseTernaryExpression)
+	p.registerInfix(token.PIPE, p.parsePipeExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.BAR, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 
 	// read two tokens to set curToken and peekToken
 	p.nextToken()
@@ -92,15 +109,248 @@ func (p *Parser) ParseProgram() *ast.Program {
 // parse each statement based on token type
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.FUNCTION:
+		// fn followed by a name declares a function, otherwise it is a literal
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionStatement()
+		}
+		return p.parseExpressionStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.CLASS:
+		return p.parseClassStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
+// parse struct Name { field, ... }
+func (p *Parser) parseStructStatement() ast.Statement {
+	stmt := &ast.StructStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Fields = []*ast.Identifier{}
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if len(stmt.Fields) > 0 && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		field := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[field.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate field %s in struct %s", field.Value, stmt.Name.Value))
+			return nil
+		}
+		seen[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+	}
+	p.nextToken()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parse class Name extends Parent { method(params) { body } ... }
+func (p *Parser) parseClassStatement() ast.Statement {
+	stmt := &ast.ClassStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.EXTENDS) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Parent = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Methods = []*ast.FunctionLiteral{}
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		name := p.curToken.Literal
+		if seen[name] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate method %s in class %s", name, stmt.Name.Value))
+			return nil
+		}
+		seen[name] = true
+
+		method := &ast.FunctionLiteral{Token: token.Token{Type: token.FUNCTION, Literal: "fn"}, Name: name}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		method.Parameters = p.parseFunctionParameters()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		method.Body = p.parseBlockStatement()
+		stmt.Methods = append(stmt.Methods, method)
+	}
+	p.nextToken()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parse enum Name { Variant, Variant(field, ...), ... }
+func (p *Parser) parseEnumStatement() ast.Statement {
+	stmt := &ast.EnumStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Variants = []*ast.EnumVariant{}
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if len(stmt.Variants) > 0 && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if seen[variant.Name.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate variant %s in enum %s", variant.Name.Value, stmt.Name.Value))
+			return nil
+		}
+		seen[variant.Name.Value] = true
+
+		variant.Fields = []*ast.Identifier{}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			variant.Fields = p.parseFunctionParameters()
+			if variant.Fields == nil {
+				return nil
+			}
+		}
+		stmt.Variants = append(stmt.Variants, variant)
+	}
+	p.nextToken()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parse import "path" as name
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parse export followed by a let, const, fn, struct, class or enum declaration
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	switch {
+	case p.curTokenIs(token.LET), p.curTokenIs(token.CONST):
+		declaration := p.parseLetStatement()
+		if declaration == nil {
+			return nil
+		}
+		stmt.Declaration = declaration
+	case p.curTokenIs(token.FUNCTION) && p.peekTokenIs(token.IDENT):
+		declaration := p.parseFunctionStatement()
+		if declaration == nil {
+			return nil
+		}
+		stmt.Declaration = declaration
+	case p.curTokenIs(token.STRUCT):
+		declaration := p.parseStructStatement()
+		if declaration == nil {
+			return nil
+		}
+		stmt.Declaration = declaration
+	case p.curTokenIs(token.CLASS):
+		declaration := p.parseClassStatement()
+		if declaration == nil {
+			return nil
+		}
+		stmt.Declaration = declaration
+	case p.curTokenIs(token.ENUM):
+		declaration := p.parseEnumStatement()
+		if declaration == nil {
+			return nil
+		}
+		stmt.Declaration = declaration
+	default:
+		msg := fmt.Sprintf("expected a let, const, fn, struct, class or enum declaration after export, got %s instead", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return stmt
+}
+
 // method to be called by parseStatement()
 // first create *ast.LetStatement node
 // use ident token to create identifier node
@@ -121,6 +371,11 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
 
+	// remember the name a function is bound to so errors can refer to it
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = stmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -212,8 +467,12 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 const (
 	_ int = iota // gives following constants incrementing numbers as values (1 - 7)
 	LOWEST
+	ASSIGN      // x = v or x += v
+	TERNARY     // c ? a : b
+	COALESCE    // ??
 	EQUALS      // ==
 	LESSGREATER // > or <
+	PIPE        // x |> f
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
@@ -294,6 +553,24 @@ var precedences = map[token.TokenType]int{
 	token.ASTERISK: PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.COALESCE: COALESCE,
+	token.QUESTION: TERNARY,
+	token.PIPE:     PIPE,
+	// assignments are the loosest of all and group to the right
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	// in tests membership like a comparison, and like in go
+	// & binds as tightly as * and | as tightly as +
+	token.IN:        LESSGREATER,
+	token.BAR:       SUM,
+	token.AMPERSAND: PRODUCT,
+
+	token.OPTIONAL_LBRACKET: INDEX,
+	token.OPTIONAL_DOT:      INDEX,
+	token.DOT:               INDEX,
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -335,18 +612,103 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+// parse null
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.Null{Token: p.curToken}
+}
+
 // parse grouped expressions
+// a parenthesized list followed by => is the parameter list of an arrow function instead
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	// () can only start an arrow function without parameters
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		return p.parseArrowFunction([]ast.Expression{})
+	}
+
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
 
+	// and so can a comma separated list
+	if p.peekTokenIs(token.COMMA) {
+		params := []ast.Expression{exp}
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			params = append(params, p.parseExpression(LOWEST))
+		}
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		return p.parseArrowFunction(params)
+	}
+
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		return p.parseArrowFunction([]ast.Expression{exp})
+	}
+
 	return exp
 }
 
+// parse the body of (x, y) => x + y with the parameters already parsed and
+// the parser sitting on the => token
+// the body is a single expression, or a block when it starts with {
+// either way it becomes a regular function literal
+func (p *Parser) parseArrowFunction(params []ast.Expression) ast.Expression {
+	lit := &ast.FunctionLiteral{Token: token.Token{Type: token.FUNCTION, Literal: "fn"}}
+
+	lit.Parameters = []*ast.Identifier{}
+	for _, param := range params {
+		if param == nil {
+			return nil
+		}
+		ident, ok := param.(*ast.Identifier)
+		if !ok {
+			msg := fmt.Sprintf("arrow function parameters must be identifiers, got %s", param)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		lit.Parameters = append(lit.Parameters, ident)
+	}
+
+	lit.Body = p.parseArrowBody()
+	if lit.Body == nil {
+		return nil
+	}
+
+	return lit
+}
+
+// parse what follows =>, a block when it starts with { and otherwise
+// a single expression wrapped in a block
+func (p *Parser) parseArrowBody() *ast.BlockStatement {
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		return p.parseBlockStatement()
+	}
+
+	p.nextToken()
+	body := &ast.ExpressionStatement{Token: p.curToken}
+	body.Expression = p.parseExpression(LOWEST)
+	if body.Expression == nil {
+		return nil
+	}
+
+	return &ast.BlockStatement{Token: body.Token, Statements: []ast.Statement{body}}
+}
+
 // parse if expression
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.curToken}
@@ -392,6 +754,9 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
+		if _, ok := stmt.(*ast.ExportStatement); ok {
+			p.errors = append(p.errors, "export is only allowed at the top level of a module")
+		}
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
@@ -420,6 +785,35 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
+// parse fn name(params) { body }
+func (p *Parser) parseFunctionStatement() ast.Statement {
+	stmt := &ast.FunctionStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	lit := &ast.FunctionLiteral{Token: stmt.Token, Name: stmt.Name.Value}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseBlockStatement()
+	stmt.Function = lit
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // create a slice of parameters by repeatedly building identifier nodes from comma separated list
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
@@ -488,6 +882,13 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	return array
 }
 
+// parse set literals, #{1, 2, 3}
+func (p *Parser) parseSetLiteral() ast.Expression {
+	set := &ast.SetLiteral{Token: p.curToken}
+	set.Elements = p.parseExpressionList(token.RBRACE)
+	return set
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
@@ -516,6 +917,7 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	// create an AST node for IndexExpression
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	exp.Optional = p.curTokenIs(token.OPTIONAL_LBRACKET)
 
 	p.nextToken()
 	// parse the expression inside the brackets and assign it to Index field of node
@@ -529,10 +931,129 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parse a.name and a?.name
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Left: left, Optional: p.curTokenIs(token.OPTIONAL_DOT)}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+// parse target = value and the compound assignments
+// only names and members can be assigned to
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{Token: p.curToken, Target: target, Operator: p.curToken.Literal}
+
+	switch target := target.(type) {
+	case nil:
+		return nil
+	case *ast.Identifier:
+	case *ast.MemberExpression:
+		if target.Optional {
+			p.errors = append(p.errors, fmt.Sprintf("cannot assign to %s", target.String()))
+			return nil
+		}
+	default:
+		p.errors = append(p.errors, fmt.Sprintf("cannot assign to %s", target.String()))
+		return nil
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGN - 1)
+
+	return exp
+}
+
+// parse match value { pattern => result, ... }
+// the comma after an arm is optional
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	if exp.Value == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	exp.Arms = []*ast.MatchArm{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.EOF) {
+			p.peekError(token.RBRACE)
+			return nil
+		}
+		p.nextToken()
+
+		arm := &ast.MatchArm{Pattern: p.parseExpression(LOWEST)}
+		if arm.Pattern == nil {
+			return nil
+		}
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		arm.Body = p.parseArrowBody()
+		if arm.Body == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+	}
+	p.nextToken()
+
+	return exp
+}
+
+// parse c ? a : b
+// the alternative is parsed with the lowest precedence so nested ternaries group to the right
+func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.TernaryExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	exp.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	exp.Alternative = p.parseExpression(LOWEST)
+
+	return exp
+}
+
+// x |> f is turned into the call f(x) and x |> f(a) into f(x, a)
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	right := p.parseExpression(precedence)
+	if right == nil {
+		return nil
+	}
+
+	if call, ok := right.(*ast.CallExpression); ok {
+		call.Arguments = append([]ast.Expression{left}, call.Arguments...)
+		return call
+	}
+
+	return &ast.CallExpression{Token: tok, Function: right, Arguments: []ast.Expression{left}}
+}
+
 // parse hash literals
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Pairs = []ast.HashLiteralPair{}
 
 	// Check for empty hash first
 	if p.peekTokenIs(token.RBRACE) {
@@ -551,7 +1072,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
 
 		if p.peekTokenIs(token.RBRACE) {
 			break
@@ -3,6 +3,7 @@ package parser
 import (
 	"pika/ast"
 	"pika/lexer"
+	"pika/token"
 	"strconv"
 	"testing"
 )
@@ -79,6 +80,103 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	t.FailNow()
 }
 
+func TestConstStatements(t *testing.T) {
+	input := `
+const x = 5;
+const answer = x * 8 + 2;
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	expected := []string{"const x = 5;", "const answer = ((x * 8) + 2);"}
+	for i, stmt := range program.Statements {
+		constStmt, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			t.Errorf("stmt not *ast.LetStatement. got=%T", stmt)
+			continue
+		}
+		if constStmt.Token.Type != token.CONST {
+			t.Errorf("constStmt.Token.Type not CONST, got %q", constStmt.Token.Type)
+		}
+		if constStmt.String() != expected[i] {
+			t.Errorf("constStmt.String() wrong, want %q, got %q", expected[i], constStmt.String())
+		}
+	}
+}
+
+func TestImportExportStatements(t *testing.T) {
+	input := `
+import "lib/math.pika" as m;
+export let x = 1;
+export const y = 2;
+export fn f(a) { a }
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{
+		`import "lib/math.pika" as m;`,
+		"export let x = 1;",
+		"export const y = 2;",
+		"export fn f(a) a",
+	}
+
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d",
+			len(expected), len(program.Statements))
+	}
+
+	importStmt, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+	if importStmt.Path.Value != "lib/math.pika" {
+		t.Errorf("import path wrong, got %q", importStmt.Path.Value)
+	}
+	testIdentifier(t, importStmt.Name, "m")
+
+	for i, stmt := range program.Statements {
+		if stmt.String() != expected[i] {
+			t.Errorf("stmt.String() wrong, want %q, got %q", expected[i], stmt.String())
+		}
+	}
+}
+
+func TestImportExportErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`import "a.pika";`, "expected next token to be be AS, got ; instead"},
+		{`import a as b;`, "expected next token to be be STRING, got IDENT instead"},
+		{`export 1;`, "expected a let, const, fn, struct, class or enum declaration after export, got INT instead"},
+		{`if (true) { export let a = 1; }`, "export is only allowed at the top level of a module"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong parser error for %q, want %q, got %q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	input := `
 return 5;
@@ -292,6 +390,46 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
 		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
 		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+		{"a ?? b == c", "(a ?? (b == c))"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"a?.b?[c] ?? null", "(((a?.b)?[c]) ?? null)"},
+		{"a.b.c(d).e", "(((a.b).c)(d).e)"},
+		{"-a.b", "(-(a.b))"},
+		{"a.b[0] + c?.d", "(((a.b)[0]) + (c?.d))"},
+		{"-a?[0]", "(-(a?[0]))"},
+		{"a * [1, 2]?[b * c] * d", "((a * ([1, 2]?[(b * c)])) * d)"},
+		{"c ?[1] : [2]", "(c ? [1] : [2])"},
+		{"c ? [1] : [2]", "(c ? [1] : [2])"},
+		{"c?[1] ?? [2]", "((c?[1]) ?? [2])"},
+		{"a < b ? a : b", "((a < b) ? a : b)"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e))"},
+		{"a ? b ? c : d : e", "(a ? (b ? c : d) : e)"},
+		{"a ?? b ? c + 1 : d", "((a ?? b) ? (c + 1) : d)"},
+		{"x |> f", "f(x)"},
+		{"x |> f |> g(1)", "g(f(x), 1)"},
+		{"x + 1 |> f", "f((x + 1))"},
+		{"x |> f == y", "(f(x) == y)"},
+		{"x |> f ? a : b", "(f(x) ? a : b)"},
+		{"[1, 2] |> map(double) |> first", "first(map([1, 2], double))"},
+		{"map(arr, (x) => x * 2)", "map(arr, fn(x) (x * 2))"},
+		{"(x, y) => x + y", "fn(x, y) (x + y)"},
+		{"() => 1", "fn() 1"},
+		{"(x) => (y) => x + y", "fn(x) fn(y) (x + y)"},
+		{"(x) => x |> f", "fn(x) f(x)"},
+		{"(x) => { x; y }", "fn(x) xy"},
+		{"(a + b) * c", "((a + b) * c)"},
+		{"#{1, 2 + 3}", "#{1, (2 + 3)}"},
+		{"#{}", "#{}"},
+		{"a | b & c", "(a | (b & c))"},
+		{"a | b - c", "((a | b) - c)"},
+		{"x in a | b", "(x in (a | b))"},
+		{"x in s == true", "((x in s) == true)"},
+		{"a | b |> len", "len((a | b))"},
+		{"x = y = 1 + 2", "(x = (y = (1 + 2)))"},
+		{"self.n += a * b", "((self.n) += (a * b))"},
+		{"x = c ? a : b", "(x = (c ? a : b))"},
+		{"x -= f(y) |> g", "(x -= g(f(y)))"},
+		{"a.x = b.y = 1 + 2", "((a.x) = ((b.y) = (1 + 2)))"},
 	}
 
 	for _, tt := range tests {
@@ -392,6 +530,80 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestFunctionLiteralWithName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements is not %d, got %d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.LetStatement, got %T", program.Statements[0])
+	}
+
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not *ast.FunctionLiteral, got %T", stmt.Value)
+	}
+
+	if function.Name != "myFunction" {
+		t.Errorf("function literal name wrong, want 'myFunction', got %q", function.Name)
+	}
+}
+
+func TestFunctionStatementParsing(t *testing.T) {
+	input := `fn add(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements is not %d, got %d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.FunctionStatement, got %T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Name, "add") {
+		return
+	}
+
+	if stmt.Function.Name != "add" {
+		t.Errorf("function literal name wrong, want 'add', got %q", stmt.Function.Name)
+	}
+
+	if len(stmt.Function.Parameters) != 2 {
+		t.Fatalf("function parameters wrong, want 2, got %d", len(stmt.Function.Parameters))
+	}
+
+	testLiteralExpression(t, stmt.Function.Parameters[0], "x")
+	testLiteralExpression(t, stmt.Function.Parameters[1], "y")
+
+	if stmt.String() != "fn add(x, y) (x + y)" {
+		t.Errorf("stmt.String() wrong, got %q", stmt.String())
+	}
+
+	// without a name fn is still a function literal
+	l = lexer.New(`fn(x) { x }(1)`)
+	p = New(l)
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if _, ok := program.Statements[0].(*ast.ExpressionStatement); !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement, got %T", program.Statements[0])
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -421,6 +633,73 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestArrowFunctionParsing(t *testing.T) {
+	input := `(x, y) => x + y`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements is not %d, got %d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement, got %T", program.Statements[0])
+	}
+
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.FunctionLiteral, got %T", stmt.Expression)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong, want 2, got %d", len(function.Parameters))
+	}
+
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements does not have 1 statement, got %d", len(function.Body.Statements))
+	}
+
+	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("function.Body.Statement[0] is not *ast.ExpressionStatement, got %T", function.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestArrowFunctionParsingErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"(1, 2)", "expected next token to be be =>, got EOF instead"},
+		{"(a + b) => 1", "arrow function parameters must be identifiers, got (a + b)"},
+		{"() + 1", "expected next token to be be =>, got + instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong parser error for %q, want %q, got %q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 +5);"
 
@@ -455,3 +734,280 @@ func TestCallExpressionParsing(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
+
+func TestHashLiteralParsingKeepsOrder(t *testing.T) {
+	input := `{"one": 1, "two": 2, 3: "three", "one": 4}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not *ast.HashLiteral, got %T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 4 {
+		t.Fatalf("hash.Pairs has wrong length, got %d", len(hash.Pairs))
+	}
+
+	expected := "{one:1, two:2, 3:three, one:4}"
+	if hash.String() != expected {
+		t.Errorf("pairs not kept in order. expected=%q, got=%q", expected, hash.String())
+	}
+}
+
+func TestStructStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		fields   []string
+		expected string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}, "struct Point { x, y }"},
+		{"struct Unit {};", "Unit", []string{}, "struct Unit {}"},
+		{"export struct Pair { first, second }", "Pair", []string{"first", "second"}, "export struct Pair { first, second }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement, got %d", len(program.Statements))
+		}
+
+		stmt := program.Statements[0]
+		if es, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = es.Declaration
+		}
+		ss, ok := stmt.(*ast.StructStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.StructStatement, got %T", stmt)
+		}
+
+		if ss.Name.Value != tt.name {
+			t.Errorf("struct name wrong. want %s, got %s", tt.name, ss.Name.Value)
+		}
+		if len(ss.Fields) != len(tt.fields) {
+			t.Fatalf("wrong number of fields. want %d, got %d", len(tt.fields), len(ss.Fields))
+		}
+		for i, f := range tt.fields {
+			testLiteralExpression(t, ss.Fields[i], f)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestStructStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, x }", "duplicate field x in struct Point"},
+		{"struct Point { x y }", "expected next token to be be ,, got IDENT instead"},
+		{"struct { x }", "expected next token to be be IDENT, got { instead"},
+		{"a.1", "expected next token to be be IDENT, got INT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser error for %q, want %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestClassStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		parent   string
+		methods  []string
+		expected string
+	}{
+		{"class Counter { init(start) { self.n = start } inc() { self.n += 1 } }", "Counter", "", []string{"init", "inc"},
+			"class Counter { init(start) ((self.n) = start) inc() ((self.n) += 1) }"},
+		{"class Empty {};", "Empty", "", []string{}, "class Empty { }"},
+		{"export class Loud extends Counter { inc() { super.inc() } }", "Loud", "Counter", []string{"inc"},
+			"export class Loud extends Counter { inc() (super.inc)() }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement, got %d", len(program.Statements))
+		}
+
+		stmt := program.Statements[0]
+		if es, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = es.Declaration
+		}
+		cs, ok := stmt.(*ast.ClassStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.ClassStatement, got %T", stmt)
+		}
+
+		if cs.Name.Value != tt.name {
+			t.Errorf("class name wrong. want %s, got %s", tt.name, cs.Name.Value)
+		}
+		if tt.parent == "" && cs.Parent != nil {
+			t.Errorf("class should have no parent, got %s", cs.Parent.Value)
+		}
+		if tt.parent != "" && (cs.Parent == nil || cs.Parent.Value != tt.parent) {
+			t.Errorf("class parent wrong. want %s, got %v", tt.parent, cs.Parent)
+		}
+		if len(cs.Methods) != len(tt.methods) {
+			t.Fatalf("wrong number of methods. want %d, got %d", len(tt.methods), len(cs.Methods))
+		}
+		for i, m := range tt.methods {
+			if cs.Methods[i].Name != m {
+				t.Errorf("method %d wrong. want %s, got %s", i, m, cs.Methods[i].Name)
+			}
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2", "cannot assign to 1"},
+		{"a[0] = 2", "cannot assign to (a[0])"},
+		{"a?.b = 2", "cannot assign to (a?.b)"},
+		{"f() += 1", "cannot assign to f()"},
+		{"class C { inc() {} inc() {} }", "duplicate method inc in class C"},
+		{"class C extends { }", "expected next token to be be IDENT, got { instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser error for %q, want %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestEnumStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		variants []string
+		expected string
+	}{
+		{"enum Color { Red, Green, Blue }", "Color", []string{"Red", "Green", "Blue"}, "enum Color { Red, Green, Blue }"},
+		{"enum Result { Ok(v), Err(e) };", "Result", []string{"Ok", "Err"}, "enum Result { Ok(v), Err(e) }"},
+		{"enum Shape { Rect(w, h), Dot, Unit() }", "Shape", []string{"Rect", "Dot", "Unit"}, "enum Shape { Rect(w, h), Dot, Unit }"},
+		{"export enum Never {}", "Never", []string{}, "export enum Never {}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement, got %d", len(program.Statements))
+		}
+
+		stmt := program.Statements[0]
+		if es, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = es.Declaration
+		}
+		es, ok := stmt.(*ast.EnumStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.EnumStatement, got %T", stmt)
+		}
+
+		if es.Name.Value != tt.name {
+			t.Errorf("enum name wrong. want %s, got %s", tt.name, es.Name.Value)
+		}
+		if len(es.Variants) != len(tt.variants) {
+			t.Fatalf("wrong number of variants. want %d, got %d", len(tt.variants), len(es.Variants))
+		}
+		for i, v := range tt.variants {
+			testLiteralExpression(t, es.Variants[i].Name, v)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		arms     int
+		expected string
+	}{
+		{"match r { Result.Ok(v) => v + 1, Result.Err(e) => 0 }", 2,
+			"match r { (Result.Ok)(v) => (v + 1), (Result.Err)(e) => 0 }"},
+		{"match (c) { Color.Red => { let x = 1; x } _ => 2, }", 2,
+			"match c { (Color.Red) => let x = 1;x, _ => 2 }"},
+		{"let n = match x { 1 => \"one\" n => n * 2 };", 2,
+			"let n = match x { 1 => one, n => (n * 2) };"},
+		{"match x {}", 0, "match x {}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement, got %d", len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestEnumAndMatchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum Color { Red, Red }", "duplicate variant Red in enum Color"},
+		{"enum Color { Red Green }", "expected next token to be be ,, got IDENT instead"},
+		{"enum Result { Ok(v }", "expected next token to be be ), got } instead"},
+		{"match x { 1 2 }", "expected next token to be be =>, got INT instead"},
+		{"match x { 1 => 2", "expected next token to be be }, got EOF instead"},
+		{"match x 1", "expected next token to be be {, got INT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser error for %q, want %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
@@ -23,10 +23,10 @@ ______ |__|  | _______
 `
 
 // func to start the repl
-func Start(in io.Reader, out io.Writer) {
+// every line is evaluated in env so bindings carry over between lines
+func Start(in io.Reader, out io.Writer, env *object.Environment) {
 	// create scanner to take in user input
 	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
 
 	// infinite read eval print loop
 	for {
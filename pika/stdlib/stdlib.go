@@ -0,0 +1,37 @@
+// standard library of pika, written in pika itself
+// every .pika file in this directory is embedded into the interpreter and its
+// functions become globals of every program, see evaluator.LoadStdlib
+package stdlib
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.pika
+var files embed.FS
+
+// a file of the standard library
+type Source struct {
+	Name string
+	Code string
+}
+
+// files of the standard library sorted by name
+func Sources() []Source {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		panic(err)
+	}
+
+	sources := []Source{}
+	for _, entry := range entries {
+		code, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		sources = append(sources, Source{Name: entry.Name(), Code: string(code)})
+	}
+
+	return sources
+}
@@ -14,46 +14,84 @@ const (
 	IDENT = "IDENT" // add, foobar, x, y, ...
 	INT   = "INT"   // 1343456
 	// Operators
-	ASSIGN   = "="
-	PLUS     = "+"
-	BANG     = "!"
-	MINUS    = "-"
-	SLASH    = "/"
-	ASTERISK = "*"
-	LT       = "<"
-	GT       = ">"
-	EQ       = "=="
-	NOT_EQ   = "!="
+	ASSIGN    = "="
+	PLUS      = "+"
+	BANG      = "!"
+	MINUS     = "-"
+	SLASH     = "/"
+	ASTERISK  = "*"
+	LT        = "<"
+	GT        = ">"
+	EQ        = "=="
+	NOT_EQ    = "!="
+	COALESCE  = "??"
+	QUESTION  = "?"
+	PIPE      = "|>"
+	ARROW     = "=>"
+	BAR       = "|"
+	AMPERSAND = "&"
+	// Compound assignment
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	// Member access and optional chaining
+	DOT               = "."
+	OPTIONAL_DOT      = "?."
+	OPTIONAL_LBRACKET = "?["
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
 	// Grouping
-	LPAREN   = "("
-	RPAREN   = ")"
-	LBRACE   = "{"
-	RBRACE   = "}"
-	LBRACKET = "["
-	RBRACKET = "]"
+	LPAREN     = "("
+	RPAREN     = ")"
+	LBRACE     = "{"
+	RBRACE     = "}"
+	LBRACKET   = "["
+	RBRACKET   = "]"
+	SET_LBRACE = "#{"
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
 	STRING   = "STRING"
+	NULL     = "NULL"
+	IMPORT   = "IMPORT"
+	AS       = "AS"
+	EXPORT   = "EXPORT"
+	IN       = "IN"
+	STRUCT   = "STRUCT"
+	CLASS    = "CLASS"
+	EXTENDS  = "EXTENDS"
+	ENUM     = "ENUM"
+	MATCH    = "MATCH"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"const":   CONST,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"null":    NULL,
+	"import":  IMPORT,
+	"as":      AS,
+	"export":  EXPORT,
+	"in":      IN,
+	"struct":  STRUCT,
+	"class":   CLASS,
+	"extends": EXTENDS,
+	"enum":    ENUM,
+	"match":   MATCH,
 }
 
 func LookupIdent(ident string) TokenType {